@@ -0,0 +1,84 @@
+// Package broker delivers published order events to the subscriptions interested in them.
+package broker
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"sse/models"
+)
+
+// Topic selects the events a subscription receives: events of an order,
+// of all user orders or all events matching the filter. Only one of the
+// fields is set, use OrderTopic, UserTopic and FilterTopic to create topics.
+type Topic struct {
+	OrderID uuid.UUID
+	UserID  uuid.UUID
+	Filter  *models.OrderFilter
+}
+
+func OrderTopic(orderID uuid.UUID) Topic {
+	return Topic{OrderID: orderID}
+}
+
+func UserTopic(userID uuid.UUID) Topic {
+	return Topic{UserID: userID}
+}
+
+func FilterTopic(filter *models.OrderFilter) Topic {
+	return Topic{Filter: filter}
+}
+
+// Message is an event delivered to a subscription.
+type Message struct {
+	Event models.EventMsg
+
+	// Coalesced is set when older queued events of the order were dropped in
+	// favor of this one because the subscriber was too slow.
+	Coalesced bool
+}
+
+type Publisher interface {
+	// Publish delivers the event to all subscriptions of its order, its user
+	// and the filters it matches. It never blocks on slow subscribers.
+	Publish(ctx context.Context, event models.EventMsg) error
+}
+
+type Subscriber interface {
+	// Subscribe creates a subscription to the topics. The subscription is
+	// closed when the context is done.
+	Subscribe(ctx context.Context, topics ...Topic) (Subscription, error)
+
+	// Subscribers returns the ids of the subscriptions to the topic.
+	Subscribers(topic Topic) []uuid.UUID
+
+	// Disconnect closes the subscription with models.ErrClientDisconnected.
+	// It reports false if there is no such subscription.
+	Disconnect(subscriptionID uuid.UUID) bool
+}
+
+type Broker interface {
+	Publisher
+	Subscriber
+}
+
+type Subscription interface {
+	ID() uuid.UUID
+
+	// Ready signals that there are messages to receive.
+	Ready() <-chan struct{}
+
+	// Receive takes all messages delivered since the last call.
+	Receive() []Message
+
+	// Add subscribes to one more topic, Remove unsubscribes from it.
+	Add(topic Topic)
+	Remove(topic Topic)
+
+	// Done is closed when the subscription is closed, Err then tells why.
+	Done() <-chan struct{}
+	Err() error
+
+	Close()
+}
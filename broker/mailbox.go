@@ -0,0 +1,74 @@
+package broker
+
+import (
+	"sync"
+
+	"sse/config"
+)
+
+// mailbox holds the events published to a subscription until they are received.
+// When the subscriber falls behind, the slow consumer policy decides whether the
+// queue grows, is coalesced or the subscriber has to be disconnected.
+type mailbox struct {
+	mu       sync.Mutex
+	messages []Message
+	ready    chan struct{} // Signals the subscriber that there are messages to receive
+}
+
+func newMailbox() *mailbox {
+	return &mailbox{
+		ready: make(chan struct{}, 1),
+	}
+}
+
+// push queues the message. It reports false if the subscriber can't keep up and
+// has to be disconnected, and coalesced if a queued message of the same order was replaced.
+func (m *mailbox) push(msg Message, cfg config.SlowConsumerConfig) (ok bool, coalesced bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.messages) >= cfg.Buffer {
+		switch cfg.Policy {
+		case config.SlowConsumerQueue:
+			if len(m.messages) >= cfg.MaxQueue {
+				return false, false
+			}
+
+		case config.SlowConsumerCoalesce:
+			for i := range m.messages {
+				if m.messages[i].Event.OrderID == msg.Event.OrderID {
+					m.messages = append(m.messages[:i], m.messages[i+1:]...)
+					msg.Coalesced = true
+					coalesced = true
+					break
+				}
+			}
+			if !coalesced && len(m.messages) >= cfg.MaxQueue {
+				return false, false
+			}
+
+		default:
+			return false, false
+		}
+	}
+
+	m.messages = append(m.messages, msg)
+
+	select {
+	case m.ready <- struct{}{}:
+	default:
+	}
+
+	return true, coalesced
+}
+
+// pop takes all queued messages.
+func (m *mailbox) pop() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	messages := m.messages
+	m.messages = nil
+
+	return messages
+}
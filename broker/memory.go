@@ -0,0 +1,299 @@
+package broker
+
+import (
+	"context"
+	"log"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+
+	"sse/config"
+	"sse/models"
+	"sse/workflow"
+)
+
+// Memory is an in-process broker. Every subscription has its own mailbox,
+// so publishing never waits for subscribers. Subscribers that can't keep up
+// are handled according to the slow consumer policy.
+type Memory struct {
+	cfg config.SlowConsumerConfig
+
+	mu            sync.Mutex
+	subscriptions map[uuid.UUID]*memorySubscription
+	byOrder       map[uuid.UUID]map[*memorySubscription]bool // Subscriptions registry by order ID
+	byUser        map[uuid.UUID]map[*memorySubscription]bool // Subscriptions registry by user ID
+	byFilter      map[*memorySubscription][]*models.OrderFilter
+
+	droppedMessages     atomic.Int64 // Messages replaced in the queues of slow subscribers
+	disconnectedClients atomic.Int64 // Slow subscribers disconnected by the broker
+}
+
+func NewMemory(cfg config.SlowConsumerConfig) *Memory {
+	return &Memory{
+		cfg:           cfg,
+		subscriptions: make(map[uuid.UUID]*memorySubscription),
+		byOrder:       make(map[uuid.UUID]map[*memorySubscription]bool),
+		byUser:        make(map[uuid.UUID]map[*memorySubscription]bool),
+		byFilter:      make(map[*memorySubscription][]*models.OrderFilter),
+	}
+}
+
+func (m *Memory) Publish(_ context.Context, event models.EventMsg) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	notified := make(map[*memorySubscription]bool)
+	notifyAll := func(subs map[*memorySubscription]bool) {
+		for sub := range subs {
+			if !notified[sub] {
+				notified[sub] = true
+				m.notify(sub, event)
+			}
+		}
+	}
+
+	notifyAll(m.byOrder[event.OrderID])
+	notifyAll(m.byUser[event.UserID])
+
+	isFinal := workflow.For(event.OrderType).IsFinal(event.OrderStatus)
+	for sub, filters := range m.byFilter {
+		for _, filter := range filters {
+			if !notified[sub] && filter.Match(&event, isFinal) {
+				notified[sub] = true
+				m.notify(sub, event)
+			}
+		}
+	}
+
+	return nil
+}
+
+// notify queues the event for the subscription without blocking.
+func (m *Memory) notify(sub *memorySubscription, event models.EventMsg) {
+	select {
+	case <-sub.done:
+		return
+	default:
+	}
+
+	ok, coalesced := sub.mailbox.push(Message{Event: event}, m.cfg)
+	if coalesced {
+		log.Printf("Slow client for order %s, older queued event replaced. %d dropped events",
+			event.OrderID, m.droppedMessages.Add(1))
+	}
+	if !ok {
+		// The subscription is removed from the registry by its owner, the broker lock is held here.
+		go sub.close(models.ErrClientDisconnected)
+		log.Printf("Slow client for order %s disconnected. %d disconnected clients",
+			event.OrderID, m.disconnectedClients.Add(1))
+	}
+}
+
+func (m *Memory) Subscribe(ctx context.Context, topics ...Topic) (Subscription, error) {
+	sub := &memorySubscription{
+		id:      uuid.New(),
+		broker:  m,
+		mailbox: newMailbox(),
+		topics:  slices.Clone(topics),
+		done:    make(chan struct{}),
+	}
+
+	m.mu.Lock()
+	m.subscriptions[sub.id] = sub
+	for _, topic := range topics {
+		m.add(sub, topic)
+	}
+	m.mu.Unlock()
+
+	stopCtx := context.AfterFunc(ctx, func() {
+		sub.close(ctx.Err())
+	})
+	sub.topicsMutex.Lock()
+	sub.stopCtx = stopCtx
+	sub.topicsMutex.Unlock()
+
+	return sub, nil
+}
+
+func (m *Memory) Subscribers(topic Topic) []uuid.UUID {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var res []uuid.UUID
+	switch {
+	case topic.OrderID != uuid.Nil:
+		for sub := range m.byOrder[topic.OrderID] {
+			res = append(res, sub.id)
+		}
+	case topic.UserID != uuid.Nil:
+		for sub := range m.byUser[topic.UserID] {
+			res = append(res, sub.id)
+		}
+	default:
+		for sub := range m.byFilter {
+			res = append(res, sub.id)
+		}
+	}
+
+	return res
+}
+
+func (m *Memory) Disconnect(subscriptionID uuid.UUID) bool {
+	m.mu.Lock()
+	sub, ok := m.subscriptions[subscriptionID]
+	m.mu.Unlock()
+
+	if ok {
+		sub.close(models.ErrClientDisconnected)
+	}
+
+	return ok
+}
+
+// add registers the subscription for the topic. m.mu must be held.
+func (m *Memory) add(sub *memorySubscription, topic Topic) {
+	switch {
+	case topic.OrderID != uuid.Nil:
+		addSubscription(m.byOrder, topic.OrderID, sub)
+		log.Printf("Client added for order %s. %d registered clients", topic.OrderID, len(m.byOrder[topic.OrderID]))
+	case topic.UserID != uuid.Nil:
+		addSubscription(m.byUser, topic.UserID, sub)
+		log.Printf("Client added for user %s. %d registered clients", topic.UserID, len(m.byUser[topic.UserID]))
+	case topic.Filter != nil:
+		m.byFilter[sub] = append(m.byFilter[sub], topic.Filter)
+		log.Printf("Client added for all events. %d registered clients", len(m.byFilter))
+	}
+}
+
+// remove unregisters the subscription from the topic. m.mu must be held.
+func (m *Memory) remove(sub *memorySubscription, topic Topic) {
+	switch {
+	case topic.OrderID != uuid.Nil:
+		removeSubscription(m.byOrder, topic.OrderID, sub)
+		log.Printf("Removed client for order %s. %d registered clients", topic.OrderID, len(m.byOrder[topic.OrderID]))
+	case topic.UserID != uuid.Nil:
+		removeSubscription(m.byUser, topic.UserID, sub)
+		log.Printf("Removed client for user %s. %d registered clients", topic.UserID, len(m.byUser[topic.UserID]))
+	case topic.Filter != nil:
+		m.byFilter[sub] = slices.DeleteFunc(m.byFilter[sub], func(filter *models.OrderFilter) bool {
+			return filter == topic.Filter
+		})
+		if len(m.byFilter[sub]) == 0 {
+			delete(m.byFilter, sub)
+		}
+		log.Printf("Removed client for all events. %d registered clients", len(m.byFilter))
+	}
+}
+
+func addSubscription(registry map[uuid.UUID]map[*memorySubscription]bool, key uuid.UUID, sub *memorySubscription) {
+	if _, exists := registry[key]; !exists {
+		registry[key] = make(map[*memorySubscription]bool)
+	}
+	registry[key][sub] = true
+}
+
+func removeSubscription(registry map[uuid.UUID]map[*memorySubscription]bool, key uuid.UUID, sub *memorySubscription) {
+	delete(registry[key], sub)
+	if len(registry[key]) == 0 {
+		delete(registry, key)
+	}
+}
+
+type memorySubscription struct {
+	id      uuid.UUID
+	broker  *Memory
+	mailbox *mailbox
+
+	topicsMutex sync.Mutex // Mutex to protect access to topics and stopCtx
+	topics      []Topic
+	stopCtx     func() bool
+
+	done      chan struct{}
+	closeOnce sync.Once
+	err       error
+}
+
+func (s *memorySubscription) ID() uuid.UUID {
+	return s.id
+}
+
+func (s *memorySubscription) Ready() <-chan struct{} {
+	return s.mailbox.ready
+}
+
+func (s *memorySubscription) Receive() []Message {
+	return s.mailbox.pop()
+}
+
+func (s *memorySubscription) Add(topic Topic) {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+
+	s.topicsMutex.Lock()
+	s.topics = append(s.topics, topic)
+	s.topicsMutex.Unlock()
+
+	s.broker.add(s, topic)
+}
+
+func (s *memorySubscription) Remove(topic Topic) {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	s.topicsMutex.Lock()
+	for i := range s.topics {
+		if s.topics[i] == topic {
+			s.topics = append(s.topics[:i], s.topics[i+1:]...)
+			break
+		}
+	}
+	s.topicsMutex.Unlock()
+
+	s.broker.remove(s, topic)
+}
+
+func (s *memorySubscription) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *memorySubscription) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+func (s *memorySubscription) Close() {
+	s.close(nil)
+}
+
+// close unregisters the subscription from all its topics and closes Done.
+func (s *memorySubscription) close(err error) {
+	s.closeOnce.Do(func() {
+		s.broker.mu.Lock()
+		s.topicsMutex.Lock()
+		if s.stopCtx != nil {
+			s.stopCtx()
+		}
+		for _, topic := range s.topics {
+			s.broker.remove(s, topic)
+		}
+		s.topics = nil
+		s.topicsMutex.Unlock()
+		delete(s.broker.subscriptions, s.id)
+		s.broker.mu.Unlock()
+
+		s.err = err
+		close(s.done)
+	})
+}
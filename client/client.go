@@ -0,0 +1,153 @@
+// Package client is a Go client of the order events service.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"sse/models"
+)
+
+type Config struct {
+	BaseURL    string       // e.g. http://localhost:8080
+	APIKey     string       // Sent in the X-API-Key header, the server limits connections per key
+	Secret     string       // Signs the sent webhook events, if the server verifies them
+	HTTPClient *http.Client // http.DefaultClient if nil, it must not have a timeout for streams
+
+	// Streams reconnect after MinBackoff, doubling the delay up to MaxBackoff while the
+	// server is unavailable. The retry sent by the server replaces MinBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// ErrTransitionWindowExpired is returned when the server rejects an event whose
+// status transition came after its window, e.g. a refund after the refund window.
+var ErrTransitionWindowExpired = models.ErrTransitionWindowExpired
+
+type Client struct {
+	cfg Config
+}
+
+func New(cfg Config) *Client {
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = time.Second
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = 30 * time.Second
+	}
+
+	return &Client{cfg: cfg}
+}
+
+// StatusError is returned when the server responds with an unexpected status.
+// It unwraps to the models error of the status, e.g. models.ErrAlreadyProcessed for 409,
+// or of the error code for statuses shared by several errors.
+type StatusError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	RetryAfter time.Duration // Set by the server when the request can be retried later
+}
+
+func (e *StatusError) Error() string {
+	if len(e.Message) == 0 {
+		return fmt.Sprintf("unexpected status %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Message)
+}
+
+func (e *StatusError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return models.ErrBadRequest
+	case http.StatusConflict:
+		if e.Code == models.CodeTransitionWindowExpired {
+			return ErrTransitionWindowExpired
+		}
+		return models.ErrAlreadyProcessed
+	case http.StatusGone:
+		return models.ErrAlreadyExistsFinalStatus
+	case http.StatusTooManyRequests:
+		return models.ErrTooManyClientConnections
+	case http.StatusServiceUnavailable:
+		return models.ErrTooManyConnections
+	default:
+		return nil
+	}
+}
+
+// temporary reports whether the request may succeed if it is retried.
+func (e *StatusError) temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body io.Reader) (*http.Request, error) {
+	u := c.cfg.BaseURL + path
+	if len(query) != 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(c.cfg.APIKey) != 0 {
+		req.Header.Set("X-API-Key", c.cfg.APIKey)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return req, nil
+}
+
+// do sends the request and decodes the JSON response into res, if it is not nil.
+func (c *Client) do(req *http.Request, res interface{}) error {
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return newStatusError(resp)
+	}
+
+	if res == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(res)
+}
+
+func newStatusError(resp *http.Response) *StatusError {
+	statusErr := &StatusError{StatusCode: resp.StatusCode}
+
+	var body struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&body); err == nil {
+		statusErr.Code = body.Code
+		statusErr.Message = body.Message
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		statusErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	return statusErr
+}
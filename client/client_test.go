@@ -0,0 +1,44 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sse/models"
+)
+
+func TestSendEventConflicts(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    error
+		notWant error
+	}{
+		{name: "duplicate", want: models.ErrAlreadyProcessed, notWant: ErrTransitionWindowExpired},
+		{
+			name:    "window expired",
+			body:    `{"code":"transition_window_expired","message":"order status transition window has expired"}`,
+			want:    ErrTransitionWindowExpired,
+			notWant: models.ErrAlreadyProcessed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusConflict)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			err := New(Config{BaseURL: srv.URL}).SendEvent(context.Background(), models.EventBody{})
+			if !errors.Is(err, tt.want) || errors.Is(err, tt.notWant) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
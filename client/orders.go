@@ -0,0 +1,109 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"sse/models"
+	"sse/signature"
+)
+
+// SendEvent posts a payment webhook event. It returns an error wrapping models.ErrAlreadyProcessed,
+// models.ErrAlreadyExistsFinalStatus or ErrTransitionWindowExpired if the event is rejected.
+func (c *Client) SendEvent(ctx context.Context, event models.EventBody) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/webhooks/payments/orders", nil, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if len(c.cfg.Secret) != 0 {
+		req.Header.Set(signature.Header, signature.Sign(body, time.Now(), c.cfg.Secret))
+	}
+
+	return c.do(req, nil)
+}
+
+// SendEvents posts the events in one batch webhook. A rejected event doesn't fail
+// the batch, the result of every event is returned in the order of the events.
+func (c *Client) SendEvents(ctx context.Context, events []models.EventBody) ([]models.BatchItemResult, error) {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/webhooks/payments/orders:batch", nil, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(c.cfg.Secret) != 0 {
+		req.Header.Set(signature.Header, signature.Sign(body, time.Now(), c.cfg.Secret))
+	}
+
+	var res models.BatchResponse
+	if err = c.do(req, &res); err != nil {
+		return nil, err
+	}
+
+	return res.Results, nil
+}
+
+// ListOrders returns the order events matching the filter like GET /orders.
+// Exactly one of the status and is_final conditions must be set.
+func (c *Client) ListOrders(ctx context.Context, filter models.OrderFilter) ([]models.EventBody, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/orders", filterQuery(filter), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []models.EventBody
+	if err = c.do(req, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// filterQuery encodes the set conditions of the filter as query parameters.
+func filterQuery(filter models.OrderFilter) url.Values {
+	query := url.Values{}
+	if len(filter.Status) != 0 {
+		query.Set("status", strings.Join(filter.Status, ","))
+	}
+	if len(filter.OrderType) != 0 {
+		query.Set("order_type", strings.Join(filter.OrderType, ","))
+	}
+	if filter.IsFinal != nil {
+		query.Set("is_final", strconv.FormatBool(*filter.IsFinal))
+	}
+	if filter.UserID != uuid.Nil {
+		query.Set("user_id", filter.UserID.String())
+	}
+	if filter.Limit != 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	if filter.Offset != 0 {
+		query.Set("offset", strconv.Itoa(filter.Offset))
+	}
+	if len(filter.SortBy) != 0 {
+		query.Set("sort_by", filter.SortBy)
+	}
+	if len(filter.SortOrder) != 0 {
+		query.Set("sort_order", filter.SortOrder)
+	}
+
+	return query
+}
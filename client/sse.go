@@ -0,0 +1,75 @@
+package client
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// frame is a text/event-stream frame.
+type frame struct {
+	id    string
+	event string
+	data  string
+	retry time.Duration
+}
+
+type frameReader struct {
+	r *bufio.Reader
+}
+
+func newFrameReader(r io.Reader) *frameReader {
+	return &frameReader{r: bufio.NewReader(r)}
+}
+
+// next reads the next frame. Comments, like the server pings, are skipped.
+func (f *frameReader) next() (frame, error) {
+	var (
+		res   frame
+		data  []string
+		empty = true
+	)
+
+	for {
+		line, err := f.r.ReadString('\n')
+		if err != nil {
+			return frame{}, err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		if len(line) == 0 {
+			if empty {
+				continue
+			}
+			res.data = strings.Join(data, "\n")
+			return res, nil
+		}
+
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "id":
+			res.id = value
+		case "event":
+			res.event = value
+		case "data":
+			data = append(data, value)
+		case "retry":
+			ms, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			res.retry = time.Duration(ms) * time.Millisecond
+		default:
+			continue
+		}
+		empty = false
+	}
+}
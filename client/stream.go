@@ -0,0 +1,245 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"sse/models"
+)
+
+// StreamOptions control the history replayed when a stream connects.
+// They are ignored after a reconnect, an order stream resumes after the last received event.
+type StreamOptions struct {
+	LastEventID uuid.UUID // Resume after the event
+	History     string    // none, latest, all or the number of latest events
+	Since       time.Time // Replay only events updated at or after the time
+}
+
+// Stream delivers order events until the order can no longer change, the
+// context is done or the server rejects the stream.
+type Stream struct {
+	client *Client
+	path   string
+	query  url.Values
+
+	events      chan models.EventMsg
+	lastEventID string
+	backoff     time.Duration
+	err         error
+
+	// delivered are the events and gaps already sent on events, set if the server
+	// replays them after a reconnect.
+	delivered map[string]bool
+}
+
+// Events returns the channel of the stream events. Gaps in the order statuses are
+// delivered as events with the models.EventGap order status and no event id.
+// The channel is closed when the stream ends.
+func (s *Stream) Events() <-chan models.EventMsg {
+	return s.events
+}
+
+// Err returns why the stream ended. It is nil if the order can no longer change
+// and the context error if the context is done. It must be called after Events is closed.
+func (s *Stream) Err() error {
+	return s.err
+}
+
+// LastEventID returns the id of the last received event, it can be used to resume
+// the stream later. It must be called after Events is closed.
+func (s *Stream) LastEventID() string {
+	return s.lastEventID
+}
+
+// StreamOrder streams the order events like GET /orders/{order_id}/events.
+func (c *Client) StreamOrder(ctx context.Context, orderID uuid.UUID, opts StreamOptions) *Stream {
+	return c.stream(ctx, "/orders/"+orderID.String()+"/events", opts, url.Values{})
+}
+
+// StreamUser streams events of all user orders like GET /users/{user_id}/events.
+// The server doesn't resume user streams and replays the events of the most recently
+// updated user orders on every reconnect, the stream skips the events it already delivered.
+// The options are ignored.
+func (c *Client) StreamUser(ctx context.Context, userID uuid.UUID, opts StreamOptions) *Stream {
+	s := c.newStream("/users/"+userID.String()+"/events", opts, url.Values{})
+	s.delivered = make(map[string]bool)
+
+	go s.run(ctx)
+
+	return s
+}
+
+// StreamAll streams live events of all orders matching the status, order_type,
+// is_final and user_id conditions of the filter like GET /events. There is no history,
+// events sent while the stream reconnects are lost.
+func (c *Client) StreamAll(ctx context.Context, filter models.OrderFilter) *Stream {
+	filter.Limit, filter.Offset, filter.SortBy, filter.SortOrder = 0, 0, "", ""
+
+	return c.stream(ctx, "/events", StreamOptions{}, filterQuery(filter))
+}
+
+func (c *Client) stream(ctx context.Context, path string, opts StreamOptions, query url.Values) *Stream {
+	s := c.newStream(path, opts, query)
+
+	go s.run(ctx)
+
+	return s
+}
+
+func (c *Client) newStream(path string, opts StreamOptions, query url.Values) *Stream {
+	if len(opts.History) != 0 {
+		query.Set("history", opts.History)
+	}
+	if !opts.Since.IsZero() {
+		query.Set("since", opts.Since.Format(time.RFC3339))
+	}
+
+	s := &Stream{
+		client:  c,
+		path:    path,
+		query:   query,
+		events:  make(chan models.EventMsg),
+		backoff: c.cfg.MinBackoff,
+	}
+	if opts.LastEventID != uuid.Nil {
+		s.lastEventID = opts.LastEventID.String()
+	}
+
+	return s
+}
+
+// run connects to the stream and reconnects with backoff until the stream ends.
+func (s *Stream) run(ctx context.Context) {
+	defer close(s.events)
+
+	delay := s.backoff
+	for {
+		connected, ended, err := s.connect(ctx)
+		if ended {
+			return
+		}
+		if ctx.Err() != nil {
+			s.err = ctx.Err()
+			return
+		}
+
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && !statusErr.temporary() {
+			s.err = err
+			return
+		}
+
+		if connected {
+			delay = s.backoff
+		}
+		wait := delay
+		if statusErr != nil && statusErr.RetryAfter > 0 {
+			wait = statusErr.RetryAfter
+		}
+
+		select {
+		case <-ctx.Done():
+			s.err = ctx.Err()
+			return
+		case <-time.After(wait):
+		}
+
+		delay = min(2*delay, s.client.cfg.MaxBackoff)
+	}
+}
+
+// connect reads the stream until the connection is lost. It reports whether the
+// connection was established and whether the stream has ended.
+func (s *Stream) connect(ctx context.Context) (connected bool, ended bool, err error) {
+	req, err := s.client.newRequest(ctx, http.MethodGet, s.path, s.query, nil)
+	if err != nil {
+		return false, false, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if len(s.lastEventID) != 0 {
+		req.Header.Set("Last-Event-ID", s.lastEventID)
+	}
+
+	resp, err := s.client.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return false, false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent:
+		// The order has already ended.
+		return true, true, nil
+	default:
+		return false, false, newStatusError(resp)
+	}
+
+	frames := newFrameReader(resp.Body)
+	for {
+		f, err := frames.next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return true, false, err
+		}
+
+		if f.retry > 0 {
+			s.backoff = f.retry
+		}
+
+		var (
+			eventMsg models.EventMsg
+			key      string // Identifies the event for the delivered events
+		)
+		switch f.event {
+		case "":
+			continue
+
+		case models.EventEnd:
+			return true, true, nil
+
+		case models.EventGap:
+			var gap models.Gap
+			if err = json.Unmarshal([]byte(f.data), &gap); err != nil {
+				return true, false, fmt.Errorf("decoding gap: %w", err)
+			}
+			eventMsg = models.EventMsg{OrderID: gap.OrderID, UserID: gap.UserID, OrderStatus: models.EventGap}
+			// Gaps have no event id, the same gap is sent again before the same event.
+			key = models.EventGap + ":" + gap.OrderID.String() + ":" + gap.OrderStatus
+
+		default:
+			if err = json.Unmarshal([]byte(f.data), &eventMsg); err != nil {
+				return true, false, fmt.Errorf("decoding event %s: %w", strconv.Quote(f.id), err)
+			}
+			key = eventMsg.EventID.String()
+		}
+
+		if s.delivered != nil && s.delivered[key] {
+			continue
+		}
+
+		select {
+		case s.events <- eventMsg:
+		case <-ctx.Done():
+			return true, false, ctx.Err()
+		}
+
+		if s.delivered != nil {
+			s.delivered[key] = true
+		}
+		if len(f.id) != 0 {
+			s.lastEventID = f.id
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"sse/models"
+)
+
+func TestStreamUserSkipsReplayedEvents(t *testing.T) {
+	userID, orderID := uuid.New(), uuid.New()
+	event := func(status string) models.EventMsg {
+		return models.EventMsg{EventID: uuid.New(), OrderID: orderID, UserID: userID, OrderStatus: status}
+	}
+	created, confirmed, chinazes := event(models.CoolOrderCreated), event(models.ConfirmedByMayor), event(models.Chinazes)
+	gap := models.Gap{OrderID: orderID, UserID: userID, LastOrderStatus: models.CoolOrderCreated, OrderStatus: models.ConfirmedByMayor}
+
+	connects := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connects++
+		w.Header().Set("Content-Type", "text/event-stream")
+
+		write := func(id, eventName string, data interface{}) {
+			b, _ := json.Marshal(data)
+			if len(id) != 0 {
+				fmt.Fprintf(w, "id: %s\n", id)
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventName, b)
+		}
+
+		// Every connect replays the history, the first one is lost before the last event.
+		write(created.EventID.String(), created.OrderStatus, created)
+		write("", models.EventGap, gap)
+		write(confirmed.EventID.String(), confirmed.OrderStatus, confirmed)
+		if connects == 1 {
+			return
+		}
+		write(chinazes.EventID.String(), chinazes.OrderStatus, chinazes)
+		write("", models.EventEnd, map[string]string{})
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c := New(Config{BaseURL: srv.URL, MinBackoff: time.Millisecond})
+	stream := c.StreamUser(ctx, userID, StreamOptions{})
+
+	var got []string
+	for eventMsg := range stream.Events() {
+		got = append(got, eventMsg.OrderStatus)
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{models.CoolOrderCreated, models.EventGap, models.ConfirmedByMayor, models.Chinazes}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if connects != 2 {
+		t.Fatalf("got %d connects, want 2", connects)
+	}
+}
@@ -0,0 +1,212 @@
+// Command loadgen opens SSE subscribers to generated orders, posts their webhook
+// lifecycles to a local instance and reports the webhook to stream latency,
+// dropped deliveries and errors.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+
+	"sse/client"
+	"sse/models"
+)
+
+func main() {
+	addr := flag.String("addr", "http://localhost:8080", "server address")
+	apiKey := flag.String("api-key", "", "X-API-Key of the subscribers, one of sse.admission.api_keys")
+	secret := flag.String("secret", "", "webhook signing secret")
+	ordersCount := flag.Int("orders", 50, "number of orders")
+	subscribersCount := flag.Int("subscribers", 50, "number of stream subscribers, spread evenly across the orders, the default fits the default sse.admission limits")
+	rate := flag.Float64("rate", 50, "webhooks per second")
+	cancelRate := flag.Float64("cancel", 0.1, "share of orders that fail or are canceled")
+	refundRate := flag.Float64("refund", 0.1, "share of completed orders that give the money back")
+	reorderRate := flag.Float64("reorder", 0.1, "share of events delivered before the previous status")
+	duplicateRate := flag.Float64("duplicate", 0.05, "share of events delivered twice")
+	warmup := flag.Duration("warmup", 2*time.Second, "delay between connecting the subscribers and the first webhook")
+	drain := flag.Duration("drain", 45*time.Second, "how long to wait for the streams to end after the last webhook")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "random seed of the scenario")
+	flag.Parse()
+
+	if *ordersCount <= 0 || *subscribersCount < 0 || *rate <= 0 {
+		fmt.Fprintln(os.Stderr, "orders and rate must be positive")
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	rnd := rand.New(rand.NewSource(*seed))
+	scenario := scenarioConfig{
+		cancelRate:    *cancelRate,
+		refundRate:    *refundRate,
+		reorderRate:   *reorderRate,
+		duplicateRate: *duplicateRate,
+	}
+
+	orders := make([]*order, *ordersCount)
+	for i := range orders {
+		orders[i] = newOrder(rnd, scenario)
+	}
+
+	st := newStats()
+	subscribers := &sync.WaitGroup{}
+	streamCtx, cancelStreams := context.WithCancel(ctx)
+	defer cancelStreams()
+
+	c := client.New(client.Config{BaseURL: *addr, APIKey: *apiKey})
+	for i := range *subscribersCount {
+		o := orders[i%len(orders)]
+
+		subscribers.Add(1)
+		go func() {
+			defer subscribers.Done()
+			subscribe(streamCtx, c, o, st)
+		}()
+	}
+
+	fmt.Printf("%d subscribers across %d orders, seed %d\n", *subscribersCount, len(orders), *seed)
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(*warmup):
+	}
+
+	start := time.Now()
+	publish(ctx, client.New(client.Config{BaseURL: *addr, APIKey: *apiKey, Secret: *secret}), orders, *rate, st)
+	elapsed := time.Since(start)
+
+	done := make(chan struct{})
+	go func() {
+		subscribers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	case <-time.After(*drain):
+		fmt.Fprintln(os.Stderr, "streams did not end within the drain timeout")
+	}
+	cancelStreams()
+	<-done
+
+	st.report(os.Stdout, elapsed)
+}
+
+// publish posts the events of the orders at the rate, taking the orders in turn.
+// Every order has its own sender, so its events are delivered in the planned order.
+func publish(ctx context.Context, c *client.Client, orders []*order, rate float64, st *stats) {
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+	defer ticker.Stop()
+
+	senders := &sync.WaitGroup{}
+	queues := make([]chan delivery, len(orders))
+	for i, o := range orders {
+		queues[i] = make(chan delivery, len(o.deliveries))
+
+		senders.Add(1)
+		go func() {
+			defer senders.Done()
+			for d := range queues[i] {
+				sendEvent(ctx, c, o, d, st)
+			}
+		}()
+	}
+
+	defer func() {
+		for _, queue := range queues {
+			close(queue)
+		}
+		senders.Wait()
+	}()
+
+	for step := 0; ; step++ {
+		pending := false
+		for i, o := range orders {
+			if step >= len(o.deliveries) {
+				continue
+			}
+			pending = true
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			queues[i] <- o.deliveries[step]
+		}
+
+		if !pending {
+			return
+		}
+	}
+}
+
+func sendEvent(ctx context.Context, c *client.Client, o *order, d delivery, st *stats) {
+	event := o.event(d.index)
+	eventID := o.eventIDs[d.index]
+
+	if !d.duplicate {
+		st.sentAt.Store(eventID, time.Now())
+	}
+	st.webhooksSent.Add(1)
+
+	err := c.SendEvent(ctx, event)
+	switch {
+	case err == nil && !d.duplicate:
+		st.accept(o.id, eventID)
+	case d.duplicate && errors.Is(err, models.ErrAlreadyProcessed):
+		st.duplicatesRejected.Add(1)
+	case ctx.Err() != nil:
+	default:
+		st.webhookErrors.Add(1)
+		if err == nil {
+			err = errors.New("duplicate accepted")
+		}
+		fmt.Fprintf(os.Stderr, "order %s: %s: %v\n", o.id, event.OrderStatus, err)
+	}
+}
+
+// subscribe follows the order stream until it ends and records the deliveries.
+func subscribe(ctx context.Context, c *client.Client, o *order, st *stats) {
+	stream := c.StreamOrder(ctx, o.id, client.StreamOptions{})
+
+	received := make(map[uuid.UUID]bool)
+	var latencies []time.Duration
+
+	for eventMsg := range stream.Events() {
+		if eventMsg.OrderStatus == models.EventGap {
+			st.gaps.Add(1)
+			continue
+		}
+
+		st.deliveries.Add(1)
+		if received[eventMsg.EventID] {
+			st.duplicateDelivered.Add(1)
+			continue
+		}
+		received[eventMsg.EventID] = true
+
+		if sentAt, ok := st.sentAt.Load(eventMsg.EventID); ok {
+			latencies = append(latencies, time.Since(sentAt.(time.Time)))
+		}
+	}
+
+	if err := stream.Err(); err != nil && !errors.Is(err, context.Canceled) {
+		st.streamErrors.Add(1)
+		fmt.Fprintf(os.Stderr, "order %s: stream: %v\n", o.id, err)
+	}
+
+	st.subscriberDone(o.id, received, latencies)
+}
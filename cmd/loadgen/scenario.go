@@ -0,0 +1,117 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"sse/models"
+	"sse/workflow"
+)
+
+// delivery is a webhook call of the order scenario.
+type delivery struct {
+	index     int  // Position of the event in the order lifecycle
+	duplicate bool // The event was already delivered, the server should reject it
+}
+
+// order is a generated order lifecycle and the order its events are delivered in.
+type order struct {
+	id     uuid.UUID
+	userID uuid.UUID
+
+	statuses   []string
+	eventIDs   []uuid.UUID
+	deliveries []delivery
+
+	mu         sync.Mutex
+	updatedAts []time.Time // Assigned when the events are sent
+}
+
+type scenarioConfig struct {
+	cancelRate    float64 // Share of orders that fail or are canceled
+	refundRate    float64 // Share of completed orders that give the money back
+	reorderRate   float64 // Share of events delivered before the previous one
+	duplicateRate float64 // Share of events delivered twice
+}
+
+var progress = []string{models.CoolOrderCreated, models.SBUVarificationPending, models.ConfirmedByMayor}
+
+func newOrder(rnd *rand.Rand, cfg scenarioConfig) *order {
+	o := &order{
+		id:     uuid.New(),
+		userID: uuid.New(),
+	}
+
+	switch {
+	case rnd.Float64() < cfg.cancelRate:
+		cancel := models.Failed
+		if rnd.Intn(2) == 0 {
+			cancel = models.ChangedMyMind
+		}
+		o.statuses = append(append(o.statuses, progress[:1+rnd.Intn(len(progress))]...), cancel)
+
+	default:
+		o.statuses = append(append(o.statuses, progress...), models.Chinazes)
+		if rnd.Float64() < cfg.refundRate {
+			o.statuses = append(o.statuses, models.GiveMyMoneyBack)
+		}
+	}
+
+	for i := range o.statuses {
+		o.eventIDs = append(o.eventIDs, uuid.New())
+		o.deliveries = append(o.deliveries, delivery{index: i})
+	}
+
+	// A final status ends the stream, so only events followed by a non-final status are delivered late.
+	for i := 0; i+1 < len(o.deliveries); i++ {
+		if !workflow.For(models.DefaultOrderType).IsFinal(o.statuses[i+1]) && rnd.Float64() < cfg.reorderRate {
+			o.deliveries[i], o.deliveries[i+1] = o.deliveries[i+1], o.deliveries[i]
+			i++
+		}
+	}
+
+	deliveries := make([]delivery, 0, len(o.deliveries))
+	for _, d := range o.deliveries {
+		deliveries = append(deliveries, d)
+		if rnd.Float64() < cfg.duplicateRate {
+			deliveries = append(deliveries, delivery{index: d.index, duplicate: true})
+		}
+	}
+	o.deliveries = deliveries
+
+	return o
+}
+
+// updatedAt returns the update time of the event. Events are at least a
+// second apart as timestamps have a second precision, and an event delivered
+// early gets its time after the times of the events before it.
+func (o *order) updatedAt(index int) time.Time {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for len(o.updatedAts) <= index {
+		t := time.Now().UTC().Truncate(time.Second)
+		if l := len(o.updatedAts); l != 0 && !t.After(o.updatedAts[l-1]) {
+			t = o.updatedAts[l-1].Add(time.Second)
+		}
+		o.updatedAts = append(o.updatedAts, t)
+	}
+
+	return o.updatedAts[index]
+}
+
+func (o *order) event(index int) models.EventBody {
+	updatedAt := o.updatedAt(index).Format(models.TimeFormat)
+
+	return models.EventBody{
+		EventID:     o.eventIDs[index].String(),
+		OrderID:     o.id.String(),
+		UserID:      o.userID.String(),
+		OrderStatus: o.statuses[index],
+		UpdatedAt:   updatedAt,
+		CreatedAt:   o.updatedAt(0).Format(models.TimeFormat),
+	}
+}
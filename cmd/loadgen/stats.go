@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type stats struct {
+	webhooksSent       atomic.Int64
+	webhooksAccepted   atomic.Int64
+	duplicatesRejected atomic.Int64
+	webhookErrors      atomic.Int64
+
+	streamErrors       atomic.Int64
+	deliveries         atomic.Int64
+	duplicateDelivered atomic.Int64
+	gaps               atomic.Int64
+
+	sentAt sync.Map // Event id to the time the webhook was sent
+
+	mu        sync.Mutex
+	accepted  map[uuid.UUID][]uuid.UUID          // Accepted event ids by order id
+	received  map[uuid.UUID][]map[uuid.UUID]bool // Event ids received by every subscriber of the order
+	latencies []time.Duration
+}
+
+func newStats() *stats {
+	return &stats{
+		accepted: make(map[uuid.UUID][]uuid.UUID),
+		received: make(map[uuid.UUID][]map[uuid.UUID]bool),
+	}
+}
+
+func (s *stats) accept(orderID, eventID uuid.UUID) {
+	s.webhooksAccepted.Add(1)
+
+	s.mu.Lock()
+	s.accepted[orderID] = append(s.accepted[orderID], eventID)
+	s.mu.Unlock()
+}
+
+// subscriberDone records what a subscriber of the order has received.
+func (s *stats) subscriberDone(orderID uuid.UUID, received map[uuid.UUID]bool, latencies []time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latencies = append(s.latencies, latencies...)
+	s.received[orderID] = append(s.received[orderID], received)
+}
+
+// drops counts the accepted events the subscribers have not received.
+func (s *stats) drops() (drops, expected int) {
+	for orderID, subscribers := range s.received {
+		for _, received := range subscribers {
+			for _, eventID := range s.accepted[orderID] {
+				expected++
+				if !received[eventID] {
+					drops++
+				}
+			}
+		}
+	}
+
+	return drops, expected
+}
+
+func (s *stats) report(w io.Writer, elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fmt.Fprintf(w, "webhooks:   %d sent in %s (%.1f/s), %d accepted, %d duplicates rejected, %d errors\n",
+		s.webhooksSent.Load(), elapsed.Round(time.Millisecond), float64(s.webhooksSent.Load())/elapsed.Seconds(),
+		s.webhooksAccepted.Load(), s.duplicatesRejected.Load(), s.webhookErrors.Load())
+	drops, expected := s.drops()
+	fmt.Fprintf(w, "streams:    %d deliveries of %d expected, %d dropped, %d delivered twice, %d gaps, %d errors\n",
+		s.deliveries.Load(), expected, drops, s.duplicateDelivered.Load(), s.gaps.Load(), s.streamErrors.Load())
+
+	if len(s.latencies) == 0 {
+		return
+	}
+
+	slices.Sort(s.latencies)
+	fmt.Fprintf(w, "latency:    p50 %s, p90 %s, p99 %s, max %s\n",
+		percentile(s.latencies, 50), percentile(s.latencies, 90), percentile(s.latencies, 99),
+		s.latencies[len(s.latencies)-1])
+}
+
+// percentile returns the p-th percentile of the sorted latencies.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted)*p+99)/100 - 1
+
+	return sorted[max(i, 0)].Round(time.Microsecond)
+}
@@ -0,0 +1,75 @@
+// Command ssectl tails order streams, queries orders and posts webhook events.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"sse/client"
+)
+
+const usage = `Usage: ssectl [-addr URL] [-api-key KEY] [-secret SECRET] <command> [flags]
+
+Commands:
+  tail <order_id>   pretty-print the order stream
+  orders            query GET /orders
+  send              post an event with generated ids and timestamps
+  lifecycle         drive an order through the valid status path
+
+Run ssectl <command> -h for the command flags.
+`
+
+type command func(ctx context.Context, c *client.Client, args []string) error
+
+var commands = map[string]command{
+	"tail":      tail,
+	"orders":    orders,
+	"send":      send,
+	"lifecycle": lifecycle,
+}
+
+func main() {
+	flags := flag.NewFlagSet("ssectl", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	addr := flags.String("addr", envOr("SSE_ADDR", "http://localhost:8080"), "server address, $SSE_ADDR")
+	apiKey := flags.String("api-key", os.Getenv("SSE_API_KEY"), "X-API-Key header, $SSE_API_KEY")
+	secret := flags.String("secret", os.Getenv("SSE_WEBHOOK_SECRET"), "webhook signing secret, $SSE_WEBHOOK_SECRET")
+	_ = flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", flags.Arg(0))
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c := client.New(client.Config{BaseURL: *addr, APIKey: *apiKey, Secret: *secret})
+	if err := cmd(ctx, c, flags.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", flags.Arg(0), err)
+		stop()
+		os.Exit(1)
+	}
+}
+
+func envOr(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+
+	return fallback
+}
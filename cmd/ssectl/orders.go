@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/uuid"
+
+	"sse/client"
+	"sse/models"
+)
+
+func orders(ctx context.Context, c *client.Client, args []string) error {
+	flags := flag.NewFlagSet("orders", flag.ExitOnError)
+	status := flags.String("status", "", "comma separated list of statuses")
+	orderType := flags.String("type", "", "comma separated list of order types")
+	final := flags.String("final", "", "true or false, instead of -status")
+	user := flags.String("user", "", "user id")
+	limit := flags.Int("limit", 10, "max number of events")
+	offset := flags.Int("offset", 0, "number of events to skip")
+	sortBy := flags.String("sort-by", models.SortByCreatedAt, "created_at or updated_at")
+	sortOrder := flags.String("sort-order", models.OrderDESC, "ASC or DESC")
+	_ = flags.Parse(args)
+
+	filter := models.OrderFilter{
+		Limit:     *limit,
+		Offset:    *offset,
+		SortBy:    *sortBy,
+		SortOrder: *sortOrder,
+	}
+	if len(*status) != 0 {
+		filter.Status = strings.Split(*status, ",")
+	}
+	if len(*orderType) != 0 {
+		filter.OrderType = strings.Split(*orderType, ",")
+	}
+	if len(*final) != 0 {
+		isFinal, err := strconv.ParseBool(*final)
+		if err != nil {
+			return err
+		}
+		filter.IsFinal = &isFinal
+	}
+	if len(*user) != 0 {
+		userID, err := uuid.Parse(*user)
+		if err != nil {
+			return err
+		}
+		filter.UserID = userID
+	}
+
+	if err := filter.Validate(); err != nil {
+		return fmt.Errorf("invalid filter: %w", err)
+	}
+
+	res, err := c.ListOrders(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ORDER\tUSER\tTYPE\tSTATUS\tUPDATED\tEVENT")
+	for _, event := range res {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			event.OrderID, event.UserID, event.OrderType, event.OrderStatus, event.UpdatedAt, event.EventID)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"sse/client"
+	"sse/models"
+)
+
+// defaultPath is the valid status path of a payment order.
+var defaultPath = []string{
+	models.CoolOrderCreated,
+	models.SBUVarificationPending,
+	models.ConfirmedByMayor,
+	models.Chinazes,
+}
+
+// eventFlags are the flags of the generated event, ids are generated if not set.
+type eventFlags struct {
+	orderID   *string
+	userID    *string
+	orderType *string
+}
+
+func newEventFlags(flags *flag.FlagSet) eventFlags {
+	return eventFlags{
+		orderID:   flags.String("order", "", "order id, generated if empty"),
+		userID:    flags.String("user", "", "user id, generated if empty"),
+		orderType: flags.String("type", "", "order type, "+models.DefaultOrderType+" if empty"),
+	}
+}
+
+func (f eventFlags) event() (models.EventBody, error) {
+	event := models.EventBody{
+		OrderID:   *f.orderID,
+		UserID:    *f.userID,
+		OrderType: *f.orderType,
+	}
+
+	for _, id := range []*string{&event.OrderID, &event.UserID} {
+		if len(*id) == 0 {
+			*id = uuid.NewString()
+		} else if _, err := uuid.Parse(*id); err != nil {
+			return models.EventBody{}, err
+		}
+	}
+
+	return event, nil
+}
+
+func send(ctx context.Context, c *client.Client, args []string) error {
+	flags := flag.NewFlagSet("send", flag.ExitOnError)
+	eventFlags := newEventFlags(flags)
+	status := flags.String("status", models.CoolOrderCreated, "order status")
+	at := flags.String("at", "", "updated_at in RFC 3339, now if empty")
+	_ = flags.Parse(args)
+
+	event, err := eventFlags.event()
+	if err != nil {
+		return err
+	}
+
+	updatedAt := time.Now()
+	if len(*at) != 0 {
+		if updatedAt, err = time.Parse(time.RFC3339, *at); err != nil {
+			return err
+		}
+	}
+
+	return sendStatus(ctx, c, event, *status, updatedAt)
+}
+
+func lifecycle(ctx context.Context, c *client.Client, args []string) error {
+	flags := flag.NewFlagSet("lifecycle", flag.ExitOnError)
+	eventFlags := newEventFlags(flags)
+	path := flags.String("path", strings.Join(defaultPath, ","), "comma separated statuses to send in order")
+	interval := flags.Duration("interval", time.Second, "delay between the statuses")
+	_ = flags.Parse(args)
+
+	event, err := eventFlags.event()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("order %s, tail it with: ssectl tail %s\n", event.OrderID, event.OrderID)
+
+	// Timestamps have a second precision, so the statuses are at least a second apart.
+	updatedAt := time.Now()
+	for i, status := range strings.Split(*path, ",") {
+		if i != 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(*interval):
+			}
+			updatedAt = updatedAt.Add(time.Second)
+			if now := time.Now(); now.After(updatedAt) {
+				updatedAt = now
+			}
+		}
+
+		if err = sendStatus(ctx, c, event, status, updatedAt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sendStatus(ctx context.Context, c *client.Client, event models.EventBody, status string, updatedAt time.Time) error {
+	event.EventID = uuid.NewString()
+	event.OrderStatus = status
+	event.UpdatedAt = updatedAt.UTC().Format(models.TimeFormat)
+	event.CreatedAt = time.Now().UTC().Format(models.TimeFormat)
+
+	if err := c.SendEvent(ctx, event); err != nil {
+		return fmt.Errorf("%s: %w", status, err)
+	}
+
+	fmt.Printf("sent %-26s order=%s user=%s event=%s updated_at=%s\n",
+		status, event.OrderID, event.UserID, event.EventID, event.UpdatedAt)
+
+	return nil
+}
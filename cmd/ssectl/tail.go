@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"sse/client"
+	"sse/models"
+)
+
+func tail(ctx context.Context, c *client.Client, args []string) error {
+	flags := flag.NewFlagSet("tail", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: ssectl tail [flags] <order_id>")
+		flags.PrintDefaults()
+	}
+	history := flags.String("history", "", "history to replay: none, latest, all or the number of latest events")
+	since := flags.String("since", "", "replay only events updated at or after the RFC 3339 time")
+	lastEventID := flags.String("last-event-id", "", "resume after the event")
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return models.ErrBadRequest
+	}
+
+	orderID, err := uuid.Parse(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	opts := client.StreamOptions{History: *history}
+	if len(*since) != 0 {
+		if opts.Since, err = time.Parse(time.RFC3339, *since); err != nil {
+			return err
+		}
+	}
+	if len(*lastEventID) != 0 {
+		if opts.LastEventID, err = uuid.Parse(*lastEventID); err != nil {
+			return err
+		}
+	}
+
+	stream := c.StreamOrder(ctx, orderID, opts)
+	for eventMsg := range stream.Events() {
+		printEvent(eventMsg)
+	}
+
+	if err = stream.Err(); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	fmt.Println("-- end of stream, last event", stream.LastEventID())
+
+	return nil
+}
+
+func printEvent(eventMsg models.EventMsg) {
+	if eventMsg.OrderStatus == models.EventGap {
+		fmt.Println("-- gap, skipped statuses never arrived")
+		return
+	}
+
+	orderType := eventMsg.OrderType
+	if len(orderType) == 0 {
+		orderType = models.DefaultOrderType
+	}
+
+	fmt.Printf("%s  %-26s event=%s user=%s type=%s\n",
+		eventMsg.UpdatedAt.Format(models.TimeFormat), eventMsg.OrderStatus, eventMsg.EventID, eventMsg.UserID, orderType)
+}
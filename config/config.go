@@ -1,8 +1,15 @@
 package config
 
+import "fmt"
+
 type AppConfig struct {
 	Postgres   PostgresConfig   `json:"postgres"`
 	HTTPServer HTTPServerConfig `json:"http_server"`
+	GRPCServer GRPCServerConfig `json:"grpc_server"`
+	Admin      AdminConfig      `json:"admin"`
+	Webhook    WebhookConfig    `json:"webhook"`
+	SSE        SSEConfig        `json:"sse"`
+	Outbox     OutboxConfig     `json:"outbox"`
 }
 
 type PostgresConfig struct {
@@ -16,3 +23,102 @@ type PostgresConfig struct {
 type HTTPServerConfig struct {
 	Port string `json:"port" envconfig:"PORT" default:"8080"`
 }
+
+// AdminConfig holds the bearer token of the /admin endpoints, they are disabled without it.
+type AdminConfig struct {
+	Token string `json:"token" envconfig:"ADMIN_TOKEN"`
+}
+
+// WebhookConfig holds the secrets payment webhooks are signed with. Any of the
+// secrets is accepted, so a new secret is added before the old one is removed.
+// Signatures older or newer than Tolerance are rejected, 0 means no limit.
+// If there are no secrets, webhooks are not verified.
+type WebhookConfig struct {
+	Secrets   []string `json:"secrets" envconfig:"WEBHOOK_SECRETS"`
+	Tolerance Duration `json:"tolerance" envconfig:"WEBHOOK_TOLERANCE" default:"5m"`
+}
+
+// GRPCServerConfig of the gRPC API. Calls have to send the Token as a bearer token
+// in the authorization metadata. If the Token is empty, only the read methods are served.
+type GRPCServerConfig struct {
+	Port  string `json:"port" envconfig:"GRPC_PORT" default:"9090"`
+	Token string `json:"token" envconfig:"GRPC_TOKEN"`
+}
+
+// OutboxConfig controls how often stored events are relayed from the outbox to the stream listeners.
+// Dispatched records are kept for Retention, so instances catch up on the events published while
+// they were reconnecting to the database. 0 keeps them forever.
+type OutboxConfig struct {
+	PollInterval Duration `json:"poll_interval" envconfig:"OUTBOX_POLL_INTERVAL" default:"200ms"`
+	BatchSize    int      `json:"batch_size" envconfig:"OUTBOX_BATCH_SIZE" default:"100"`
+	Retention    Duration `json:"retention" envconfig:"OUTBOX_RETENTION" default:"24h"`
+}
+
+type SSEConfig struct {
+	Retry             Duration `json:"retry" envconfig:"SSE_RETRY" default:"3s"`
+	HeartbeatInterval Duration `json:"heartbeat_interval" envconfig:"SSE_HEARTBEAT_INTERVAL" default:"15s"`
+	MaxIdle           Duration `json:"max_idle" envconfig:"SSE_MAX_IDLE" default:"1m"`
+	UserHistoryOrders int      `json:"user_history_orders" envconfig:"SSE_USER_HISTORY_ORDERS" default:"20"`
+
+	SlowConsumer SlowConsumerConfig `json:"slow_consumer"`
+	Admission    AdmissionConfig    `json:"admission"`
+	Reorder      ReorderConfig      `json:"reorder"`
+}
+
+// ReorderConfig bounds the events a stream client holds back until the previous order status arrives.
+// When GapTimeout expires or the client holds more than MaxBuffered events, they are sent after a gap marker.
+// 0 means no limit.
+type ReorderConfig struct {
+	GapTimeout  Duration `json:"gap_timeout" envconfig:"SSE_REORDER_GAP_TIMEOUT" default:"10s"`
+	MaxBuffered int      `json:"max_buffered" envconfig:"SSE_REORDER_MAX_BUFFERED" default:"20"`
+}
+
+// AdmissionConfig limits concurrent stream connections, 0 means no limit. Every order subscribed over
+// a WebSocket counts against the per order limit, up to MaxOrdersPerSocket orders per socket.
+// Clients are identified by one of APIKeys in the X-API-Key header or by the remote IP,
+// the per IP limit applies with or without a key. The remote IP is taken from
+// X-Forwarded-For when the connection comes from one of TrustedProxies (IPs or CIDRs).
+type AdmissionConfig struct {
+	MaxConnections          int      `json:"max_connections" envconfig:"SSE_MAX_CONNECTIONS" default:"10000"`
+	MaxConnectionsPerOrder  int      `json:"max_connections_per_order" envconfig:"SSE_MAX_CONNECTIONS_PER_ORDER" default:"100"`
+	MaxConnectionsPerClient int      `json:"max_connections_per_client" envconfig:"SSE_MAX_CONNECTIONS_PER_CLIENT" default:"50"`
+	MaxConnectionsPerIP     int      `json:"max_connections_per_ip" envconfig:"SSE_MAX_CONNECTIONS_PER_IP" default:"200"`
+	MaxOrdersPerSocket      int      `json:"max_orders_per_socket" envconfig:"SSE_MAX_ORDERS_PER_SOCKET" default:"50"`
+	RetryAfter              Duration `json:"retry_after" envconfig:"SSE_RETRY_AFTER" default:"10s"`
+	APIKeys                 []string `json:"api_keys" envconfig:"SSE_API_KEYS"`
+	TrustedProxies          []string `json:"trusted_proxies" envconfig:"SSE_TRUSTED_PROXIES"`
+}
+
+const (
+	SlowConsumerDisconnect = "disconnect" // disconnect the client, it resumes the stream with Last-Event-ID
+	SlowConsumerQueue      = "queue"      // grow the client queue up to MaxQueue messages
+	SlowConsumerCoalesce   = "coalesce"   // keep only the latest queued status of every order
+)
+
+// SlowConsumerConfig defines what happens when a stream client has
+// Buffer unsent messages and another event is published to it.
+type SlowConsumerConfig struct {
+	Policy   string `json:"policy" envconfig:"SSE_SLOW_CONSUMER_POLICY" default:"disconnect"`
+	Buffer   int    `json:"buffer" envconfig:"SSE_SLOW_CONSUMER_BUFFER" default:"5"`
+	MaxQueue int    `json:"max_queue" envconfig:"SSE_SLOW_CONSUMER_MAX_QUEUE" default:"100"`
+}
+
+// Validate checks that the policy is known, Buffer is positive and, for the
+// policies that queue messages, MaxQueue is not smaller than Buffer.
+func (c SlowConsumerConfig) Validate() error {
+	if c.Buffer <= 0 {
+		return fmt.Errorf("sse.slow_consumer.buffer must be positive, got %d", c.Buffer)
+	}
+
+	switch c.Policy {
+	case SlowConsumerDisconnect:
+	case SlowConsumerQueue, SlowConsumerCoalesce:
+		if c.MaxQueue < c.Buffer {
+			return fmt.Errorf("sse.slow_consumer.max_queue %d is smaller than buffer %d", c.MaxQueue, c.Buffer)
+		}
+	default:
+		return fmt.Errorf("unknown sse.slow_consumer.policy %q", c.Policy)
+	}
+
+	return nil
+}
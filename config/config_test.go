@@ -0,0 +1,28 @@
+package config
+
+import "testing"
+
+func TestSlowConsumerConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     SlowConsumerConfig
+		wantErr bool
+	}{
+		{name: "default", cfg: defaultConfig().SSE.SlowConsumer},
+		{name: "disconnect ignores max queue", cfg: SlowConsumerConfig{Policy: SlowConsumerDisconnect, Buffer: 5}},
+		{name: "queue", cfg: SlowConsumerConfig{Policy: SlowConsumerQueue, Buffer: 5, MaxQueue: 5}},
+		{name: "zero buffer", cfg: SlowConsumerConfig{Policy: SlowConsumerDisconnect, MaxQueue: 100}, wantErr: true},
+		{name: "negative buffer", cfg: SlowConsumerConfig{Policy: SlowConsumerQueue, Buffer: -1, MaxQueue: 100}, wantErr: true},
+		{name: "queue smaller than buffer", cfg: SlowConsumerConfig{Policy: SlowConsumerQueue, Buffer: 5, MaxQueue: 4}, wantErr: true},
+		{name: "coalesce queue smaller than buffer", cfg: SlowConsumerConfig{Policy: SlowConsumerCoalesce, Buffer: 5}, wantErr: true},
+		{name: "unknown policy", cfg: SlowConsumerConfig{Policy: "drop", Buffer: 5, MaxQueue: 100}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("got %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"log"
 	"os"
+	"time"
 )
 
 var Appconfig *AppConfig
@@ -12,7 +13,7 @@ const configPath = "./config.json"
 
 func Load() error {
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		log.Printf("Config file %s not found. Using default configuration.")
+		log.Printf("Config file %s not found. Using default configuration.", configPath)
 		Appconfig = defaultConfig()
 		return nil
 	}
@@ -24,12 +25,15 @@ func Load() error {
 		return err
 	}
 
+	// Fields missing in the config file keep their default values.
+	Appconfig = defaultConfig()
+
 	jsonParser := json.NewDecoder(configFile)
 	if err = jsonParser.Decode(&Appconfig); err != nil {
 		return err
 	}
 
-	return nil
+	return Appconfig.SSE.SlowConsumer.Validate()
 }
 
 func defaultConfig() *AppConfig {
@@ -44,5 +48,39 @@ func defaultConfig() *AppConfig {
 		HTTPServer: HTTPServerConfig{
 			Port: "8080",
 		},
+		Webhook: WebhookConfig{
+			Tolerance: Duration(5 * time.Minute),
+		},
+		GRPCServer: GRPCServerConfig{
+			Port: "9090",
+		},
+		SSE: SSEConfig{
+			Retry:             Duration(3 * time.Second),
+			HeartbeatInterval: Duration(15 * time.Second),
+			MaxIdle:           Duration(time.Minute),
+			UserHistoryOrders: 20,
+			SlowConsumer: SlowConsumerConfig{
+				Policy:   SlowConsumerDisconnect,
+				Buffer:   5,
+				MaxQueue: 100,
+			},
+			Admission: AdmissionConfig{
+				MaxConnections:          10000,
+				MaxConnectionsPerOrder:  100,
+				MaxConnectionsPerClient: 50,
+				MaxConnectionsPerIP:     200,
+				MaxOrdersPerSocket:      50,
+				RetryAfter:              Duration(10 * time.Second),
+			},
+			Reorder: ReorderConfig{
+				GapTimeout:  Duration(10 * time.Second),
+				MaxBuffered: 20,
+			},
+		},
+		Outbox: OutboxConfig{
+			PollInterval: Duration(200 * time.Millisecond),
+			BatchSize:    100,
+			Retention:    Duration(24 * time.Hour),
+		},
 	}
 }
@@ -2,13 +2,17 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"log"
 	"os"
 	"os/signal"
 
 	"syscall"
 
+	"sse/broker"
 	"sse/config"
+	"sse/models"
+	"sse/server/grpc"
 	"sse/server/handlers"
 	"sse/server/http"
 	"sse/service"
@@ -37,12 +41,38 @@ func main() {
 		return
 	}
 
-	services := service.New(dbConn.NewWebhookRepo(), dbConn.NewOrdersRepo())
+	webhookRepo := dbConn.NewWebhookRepo()
+	services := service.New(webhookRepo, dbConn.NewOrdersRepo(), dbConn.NewWorkflowRepo())
 
-	wh := handlers.NewWebhookHandler(services)
+	if err = services.LoadWorkflow(ctx); err != nil {
+		log.Fatal(err)
+		return
+	}
+
+	eventsBroker := broker.NewMemory(config.Appconfig.SSE.SlowConsumer)
+	wh := handlers.NewWebhookHandler(services, eventsBroker, config.Appconfig.SSE)
+
+	go webhookRepo.ListenEvents(ctx, func(payload []byte) {
+		var eventMsg models.EventMsg
+		if err := json.Unmarshal(payload, &eventMsg); err != nil {
+			log.Printf("Error unmarshalling event: %v", err)
+			return
+		}
+
+		if err := eventsBroker.Publish(ctx, eventMsg); err != nil {
+			log.Printf("Error publishing event: %v", err)
+		}
+	}, func() {
+		if err := services.LoadWorkflow(ctx); err != nil {
+			log.Printf("Error reloading workflow: %v", err)
+		}
+	})
+	go webhookRepo.RelayOutbox(ctx, config.Appconfig.Outbox)
 
-	router := http.NewController(wh, handlers.NewOrdersHandler(services))
-	httpSrv := http.NewHTPPServer(router, config.Appconfig.HTTPServer)
+	router := http.NewController(wh, handlers.NewOrdersHandler(services), handlers.NewWorkflowHandler(services),
+		config.Appconfig.Admin, config.Appconfig.Webhook)
+	grpcSrv := grpc.NewGRPCServer(grpc.NewOrderService(services, wh), config.Appconfig.GRPCServer)
+	httpSrv := http.NewHTPPServer(router, config.Appconfig.HTTPServer, grpcSrv)
 
 	httpSrv.Run(ctx)
 }
@@ -2,8 +2,21 @@ package models
 
 import "errors"
 
+// Error codes sent in the "code" field of error responses that share a status with other errors.
+const (
+	CodeTransitionWindowExpired = "transition_window_expired" // 409 for ErrTransitionWindowExpired
+)
+
 var (
 	ErrBadRequest               = errors.New("bad request")
 	ErrAlreadyExistsFinalStatus = errors.New("already exists final status of the order")
 	ErrAlreadyProcessed         = errors.New("event already processed")
+	ErrInvalidTransition        = errors.New("order status transition is not allowed")
+	ErrTransitionWindowExpired  = errors.New("order status transition window has expired")
+	ErrAlreadyExists            = errors.New("already exists")
+	ErrTooManyConnections       = errors.New("too many stream connections")
+	ErrTooManyClientConnections = errors.New("too many stream connections for the order or client")
+	ErrClientDisconnected       = errors.New("client disconnected, events are sent slower than they arrive")
+	ErrUnknownAPIKey            = errors.New("unknown API key")
+	ErrTooManySubscriptions     = errors.New("too many orders subscribed over the connection")
 )
@@ -18,20 +18,20 @@ const (
 	GiveMyMoneyBack        = "give_my_money_back"
 )
 
-const (
-	ChinazesID        = 6
-	GiveMyMoneyBackID = 7
-)
+// DefaultOrderType is the type of orders whose events have no order_type.
+const DefaultOrderType = "payment"
 
-const (
-	GiveMyMoneyBackTimeout time.Duration = 30 * time.Second
-	InactivityTimeout      time.Duration = 1 * time.Minute
-)
+// EventEnd is the stream event sent when the order can no longer change.
+const EventEnd = "end"
+
+// EventGap is the stream event sent before an event whose previous order status never arrived.
+const EventGap = "gap"
 
 type EventBody struct {
 	EventID     string `json:"event_id"`
 	OrderID     string `json:"order_id"`
 	UserID      string `json:"user_id"`
+	OrderType   string `json:"order_type,omitempty"`
 	OrderStatus string `json:"order_status"`
 	UpdatedAt   string `json:"updated_at"`
 	CreatedAt   string `json:"created_at"`
@@ -41,15 +41,25 @@ type EventMsg struct {
 	EventID     uuid.UUID `json:"event_id"`
 	OrderID     uuid.UUID `json:"order_id"`
 	UserID      uuid.UUID `json:"user_id"`
+	OrderType   string    `json:"order_type,omitempty"`
 	OrderStatus string    `json:"order_status"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	CreatedAt   time.Time `json:"created_at"`
 }
 
+// Gap tells the client that the order statuses between LastOrderStatus and OrderStatus were skipped.
+type Gap struct {
+	OrderID         uuid.UUID `json:"order_id"`
+	UserID          uuid.UUID `json:"user_id"`
+	LastOrderStatus string    `json:"last_order_status,omitempty"` // Empty if nothing was sent for the order yet
+	OrderStatus     string    `json:"order_status"`
+}
+
 type Event struct {
 	EventID       uuid.UUID `json:"event_id"`
 	OrderID       uuid.UUID `json:"order_id"`
 	UserID        uuid.UUID `json:"user_id"`
+	OrderType     string    `json:"order_type"`
 	OrderStatusID int       `json:"order_status_id"`
 	UpdatedAt     time.Time `json:"updated_at"`
 	CreatedAt     time.Time `json:"created_at"`
@@ -64,4 +74,35 @@ type FullEventInfo struct {
 	CreatedAt       time.Time `json:"created_at"`
 	OrderStatusName string    `json:"order_status_name"`
 	IsFinal         bool      `json:"is_final"`
+	OrderType       string    `json:"order_type"`
+}
+
+// EventsPage is a list of events with the cursor to request the next events.
+// Ended is set when the order can no longer change and there are no more events to request.
+type EventsPage struct {
+	Events []EventMsg `json:"events"`
+	Cursor string     `json:"cursor"`
+	Ended  bool       `json:"ended"`
+}
+
+// Results of a batch webhook item.
+const (
+	BatchAccepted      = "accepted"
+	BatchDuplicate     = "duplicate"             // The event was already processed
+	BatchFinalStatus   = "final_status_conflict" // The order already has a final status
+	BatchWindowExpired = "window_expired"        // The status transition came after its window
+	BatchInvalid       = "invalid"               // The item can't be parsed or the status transition is not allowed
+	BatchError         = "error"                 // The item wasn't stored because of a server error, it can be retried
+)
+
+// BatchItemResult is the result of the batch webhook item at Index.
+type BatchItemResult struct {
+	Index   int    `json:"index"`
+	EventID string `json:"event_id,omitempty"`
+	Result  string `json:"result"`
+	Message string `json:"message,omitempty"`
+}
+
+type BatchResponse struct {
+	Results []BatchItemResult `json:"results"`
 }
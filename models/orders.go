@@ -1,6 +1,11 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+)
 
 const (
 	SortByCreatedAt = "created_at"
@@ -12,6 +17,7 @@ const (
 
 type OrderFilter struct {
 	Status    []string  `json:"status"`
+	OrderType []string  `json:"order_type"`
 	UserID    uuid.UUID `json:"user_id"`
 	Limit     int       `json:"limit"`
 	Offset    int       `json:"offset"`
@@ -19,3 +25,56 @@ type OrderFilter struct {
 	SortBy    string    `json:"sort_by"`
 	SortOrder string    `json:"sort_order"`
 }
+
+// Match reports whether the event passes the status, order_type, is_final and
+// user_id conditions of the filter. Empty conditions match everything.
+func (f *OrderFilter) Match(eventMsg *EventMsg, isFinal bool) bool {
+	if len(f.Status) != 0 && !slices.Contains(f.Status, eventMsg.OrderStatus) {
+		return false
+	}
+
+	if len(f.OrderType) != 0 && !slices.Contains(f.OrderType, eventMsg.OrderType) {
+		return false
+	}
+
+	if f.IsFinal != nil && *f.IsFinal != isFinal {
+		return false
+	}
+
+	if f.UserID != uuid.Nil && f.UserID != eventMsg.UserID {
+		return false
+	}
+
+	return true
+}
+
+// Validate checks that exactly one of the status and is_final conditions is
+// set and the sorting is allowed. Empty sorting and limit get default values.
+func (f *OrderFilter) Validate() error {
+	if (len(f.Status) == 0) == (f.IsFinal == nil) {
+		return fmt.Errorf("%w: exactly one of status and is_final must be set", ErrBadRequest)
+	}
+
+	if f.Limit == 0 {
+		f.Limit = 10
+	}
+	if f.Limit < 0 || f.Offset < 0 {
+		return fmt.Errorf("%w: limit and offset must not be negative", ErrBadRequest)
+	}
+
+	if len(f.SortBy) == 0 {
+		f.SortBy = SortByCreatedAt
+	}
+	if f.SortBy != SortByCreatedAt && f.SortBy != SortByUpdatedAt {
+		return fmt.Errorf("%w: sort_by must be %s or %s", ErrBadRequest, SortByCreatedAt, SortByUpdatedAt)
+	}
+
+	if len(f.SortOrder) == 0 {
+		f.SortOrder = OrderDESC
+	}
+	if f.SortOrder != OrderASC && f.SortOrder != OrderDESC {
+		return fmt.Errorf("%w: sort_order must be %s or %s", ErrBadRequest, OrderASC, OrderDESC)
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// StatusTransition allows an order of the type to move from one status straight to another.
+// An empty From marks a status orders start in, an empty OrderType means DefaultOrderType.
+type StatusTransition struct {
+	OrderType string
+	From      string
+	To        string
+	Window    time.Duration // The transition is allowed only within Window after From is reached, 0 means any time
+}
+
+// WorkflowDefinition is the order statuses and the transitions allowed between them.
+type WorkflowDefinition struct {
+	Statuses    []OrderStatus
+	Transitions []StatusTransition
+}
+
+type StatusTransitionBody struct {
+	OrderType string `json:"order_type,omitempty"`
+	From      string `json:"from,omitempty"`
+	To        string `json:"to"`
+	Window    string `json:"window,omitempty"`
+}
+
+type WorkflowBody struct {
+	Statuses    []OrderStatus          `json:"statuses"`
+	Transitions []StatusTransitionBody `json:"transitions"`
+}
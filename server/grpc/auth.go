@@ -0,0 +1,93 @@
+package grpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"sse/server/grpc/orderpb"
+)
+
+const (
+	authorizationKey = "authorization"
+	reflectionPrefix = "/grpc.reflection."
+)
+
+// readOnlyMethods are served without a token if no token is configured,
+// like their HTTP counterparts. Events can't be ingested without a token,
+// as it would bypass the webhook signature check.
+var readOnlyMethods = map[string]bool{
+	orderpb.OrderService_ListOrders_FullMethodName: true,
+	orderpb.OrderService_WatchOrder_FullMethodName: true,
+}
+
+// authenticator checks the bearer token in the authorization metadata of the calls.
+type authenticator struct {
+	token string
+}
+
+func (a authenticator) authorize(ctx context.Context, method string) error {
+	// The service definitions are public, so tools can discover the service.
+	if strings.HasPrefix(method, reflectionPrefix) {
+		return nil
+	}
+
+	if len(a.token) == 0 {
+		if readOnlyMethods[method] {
+			return nil
+		}
+		return status.Error(codes.Unauthenticated, "no grpc_server.token is configured, only read methods are served")
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get(authorizationKey) {
+		reqToken, ok := strings.CutPrefix(value, "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(reqToken), []byte(a.token)) == 1 {
+			return nil
+		}
+	}
+
+	return status.Error(codes.Unauthenticated, "missing or invalid token")
+}
+
+func (a authenticator) unary(
+	ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+) (any, error) {
+	if err := a.authorize(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+func (a authenticator) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.authorize(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+
+	return handler(srv, ss)
+}
+
+// tokenCredentials sends the token as the bearer token of every call.
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{authorizationKey: "Bearer " + string(t)}, nil
+}
+
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+var _ credentials.PerRPCCredentials = tokenCredentials("")
+
+// WithToken authenticates the calls of a client connection with the grpc_server.token of the server.
+func WithToken(token string) grpc.DialOption {
+	return grpc.WithPerRPCCredentials(tokenCredentials(token))
+}
@@ -0,0 +1,85 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"sse/config"
+	"sse/server/grpc/orderpb"
+)
+
+// dial serves an order service without methods with the token and connects to it.
+func dial(t *testing.T, serverToken string, opts ...grpc.DialOption) orderpb.OrderServiceClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 16)
+	srv := NewGRPCServer(orderpb.UnimplementedOrderServiceServer{}, config.GRPCServerConfig{Token: serverToken})
+	go func() {
+		_ = srv.server.Serve(lis)
+	}()
+	t.Cleanup(srv.server.Stop)
+
+	opts = append(opts,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+	)
+	conn, err := grpc.NewClient("passthrough:///bufnet", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return orderpb.NewOrderServiceClient(conn)
+}
+
+func TestAuthentication(t *testing.T) {
+	tests := []struct {
+		name        string
+		serverToken string
+		clientToken string
+		wantIngest  codes.Code
+		wantList    codes.Code
+	}{
+		{name: "valid token", serverToken: "secret", clientToken: "secret", wantIngest: codes.Unimplemented, wantList: codes.Unimplemented},
+		{name: "invalid token", serverToken: "secret", clientToken: "other", wantIngest: codes.Unauthenticated, wantList: codes.Unauthenticated},
+		{name: "missing token", serverToken: "secret", wantIngest: codes.Unauthenticated, wantList: codes.Unauthenticated},
+		{name: "no server token", clientToken: "secret", wantIngest: codes.Unauthenticated, wantList: codes.Unimplemented},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []grpc.DialOption
+			if len(tt.clientToken) != 0 {
+				opts = append(opts, WithToken(tt.clientToken))
+			}
+			client := dial(t, tt.serverToken, opts...)
+
+			_, err := client.IngestEvent(context.Background(), &orderpb.Event{})
+			if got := status.Code(err); got != tt.wantIngest {
+				t.Errorf("IngestEvent: got %s, want %s", got, tt.wantIngest)
+			}
+
+			_, err = client.ListOrders(context.Background(), &orderpb.ListOrdersRequest{})
+			if got := status.Code(err); got != tt.wantList {
+				t.Errorf("ListOrders: got %s, want %s", got, tt.wantList)
+			}
+
+			stream, err := client.WatchOrder(context.Background(), &orderpb.WatchOrderRequest{})
+			if err == nil {
+				_, err = stream.Recv()
+			}
+			if got := status.Code(err); got != tt.wantList {
+				t.Errorf("WatchOrder: got %s, want %s", got, tt.wantList)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package grpc
+
+import (
+	"context"
+	"log"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+
+	"sse/config"
+	"sse/server/grpc/orderpb"
+)
+
+type Server struct {
+	server *grpc.Server
+	addr   string
+}
+
+func NewGRPCServer(orderService orderpb.OrderServiceServer, cfgGRPC config.GRPCServerConfig) *Server {
+	auth := authenticator{token: cfgGRPC.Token}
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(auth.unary),
+		grpc.ChainStreamInterceptor(auth.stream),
+	)
+	orderpb.RegisterOrderServiceServer(server, orderService)
+	reflection.Register(server)
+
+	return &Server{
+		server: server,
+		addr:   ":" + cfgGRPC.Port,
+	}
+}
+
+// Serve accepts connections until Shutdown is called.
+func (s *Server) Serve() error {
+	lis, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("gRPC server starts on port: %s\n", s.addr)
+
+	return s.server.Serve(lis)
+}
+
+// Shutdown waits for running calls to finish. Calls still running when the
+// context is done are canceled.
+func (s *Server) Shutdown(ctx context.Context) {
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.server.Stop()
+	}
+}
@@ -0,0 +1,174 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"sse/models"
+	"sse/server/grpc/orderpb"
+	"sse/server/handlers"
+	"sse/service"
+)
+
+// Metadata keys that identify a WatchOrder client for the connection limits,
+// the same as the X-API-Key and X-Forwarded-For headers of stream requests.
+const (
+	apiKeyMetadata       = "x-api-key"
+	forwardedForMetadata = "x-forwarded-for"
+)
+
+type orderService struct {
+	orderpb.UnimplementedOrderServiceServer
+
+	service *service.Service
+	wh      *handlers.WebhookHandler
+}
+
+func NewOrderService(s *service.Service, wh *handlers.WebhookHandler) orderpb.OrderServiceServer {
+	return &orderService{
+		service: s,
+		wh:      wh,
+	}
+}
+
+// IngestEvent stores a payment webhook event the same way as POST /webhooks/payments/orders.
+func (o *orderService) IngestEvent(ctx context.Context, req *orderpb.Event) (*orderpb.IngestEventResponse, error) {
+	eventBody := models.EventBody{
+		EventID:     req.GetEventId(),
+		OrderID:     req.GetOrderId(),
+		UserID:      req.GetUserId(),
+		OrderType:   req.GetOrderType(),
+		OrderStatus: req.GetOrderStatus(),
+		UpdatedAt:   req.GetUpdatedAt(),
+		CreatedAt:   req.GetCreatedAt(),
+	}
+
+	event, err := o.wh.ValidateEventReq(eventBody)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err = o.service.AddEvent(ctx, event, eventBody.OrderStatus); err != nil {
+		return nil, statusError(err)
+	}
+
+	return &orderpb.IngestEventResponse{}, nil
+}
+
+// ListOrders returns the orders the same way as GET /orders.
+func (o *orderService) ListOrders(ctx context.Context, req *orderpb.ListOrdersRequest) (*orderpb.ListOrdersResponse, error) {
+	filter := models.OrderFilter{
+		Status:    req.GetStatus(),
+		OrderType: req.GetOrderType(),
+		IsFinal:   req.IsFinal,
+		Limit:     int(req.GetLimit()),
+		Offset:    int(req.GetOffset()),
+		SortBy:    req.GetSortBy(),
+		SortOrder: req.GetSortOrder(),
+	}
+
+	if len(req.GetUserId()) != 0 {
+		userID, err := uuid.Parse(req.GetUserId())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		filter.UserID = userID
+	}
+
+	if err := filter.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	orders, err := o.service.ListOrders(ctx, &filter)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	res := &orderpb.ListOrdersResponse{Orders: make([]*orderpb.Event, 0, len(orders))}
+	for i := range orders {
+		res.Orders = append(res.Orders, buildEvent(&orders[i]))
+	}
+
+	return res, nil
+}
+
+// WatchOrder streams the order events the same way as GET /orders/{order_id}/events.
+func (o *orderService) WatchOrder(req *orderpb.WatchOrderRequest, stream orderpb.OrderService_WatchOrderServer) error {
+	orderID, err := uuid.Parse(req.GetOrderId())
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	var lastEventID uuid.UUID
+	if len(req.GetLastEventId()) != 0 {
+		if lastEventID, err = uuid.Parse(req.GetLastEventId()); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	var watchPeer handlers.WatchPeer
+	if p, ok := peer.FromContext(stream.Context()); ok {
+		watchPeer.RemoteAddr = p.Addr.String()
+	}
+	if md, ok := metadata.FromIncomingContext(stream.Context()); ok {
+		if apiKey := md.Get(apiKeyMetadata); len(apiKey) != 0 {
+			watchPeer.APIKey = apiKey[0]
+		}
+		watchPeer.ForwardedFor = md.Get(forwardedForMetadata)
+	}
+
+	err = o.wh.Watch(stream.Context(), orderID, lastEventID, watchPeer, func(eventMsg *models.EventMsg) error {
+		return stream.Send(buildEvent(eventMsg))
+	})
+	if err != nil {
+		return statusError(err)
+	}
+
+	return nil
+}
+
+// buildEvent converts a stream event, gaps have no event id and timestamps.
+func buildEvent(eventMsg *models.EventMsg) *orderpb.Event {
+	event := &orderpb.Event{
+		OrderId:     eventMsg.OrderID.String(),
+		UserId:      eventMsg.UserID.String(),
+		OrderType:   eventMsg.OrderType,
+		OrderStatus: eventMsg.OrderStatus,
+	}
+	if eventMsg.EventID != uuid.Nil {
+		event.EventId = eventMsg.EventID.String()
+	}
+	if !eventMsg.UpdatedAt.IsZero() {
+		event.UpdatedAt = eventMsg.UpdatedAt.Format(time.RFC3339)
+		event.CreatedAt = eventMsg.CreatedAt.Format(time.RFC3339)
+	}
+
+	return event
+}
+
+func statusError(err error) error {
+	switch {
+	case errors.Is(err, models.ErrBadRequest):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, models.ErrAlreadyExistsFinalStatus), errors.Is(err, models.ErrInvalidTransition),
+		errors.Is(err, models.ErrTransitionWindowExpired):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, models.ErrAlreadyProcessed):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, models.ErrClientDisconnected), errors.Is(err, models.ErrTooManyConnections):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, models.ErrTooManyClientConnections):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, models.ErrUnknownAPIKey):
+		return status.Error(codes.Unauthenticated, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
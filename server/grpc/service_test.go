@@ -0,0 +1,51 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"sse/models"
+	"sse/server/grpc/orderpb"
+	"sse/service"
+)
+
+type memoryOrderRepo struct {
+	orders []models.FullEventInfo
+}
+
+func (m *memoryOrderRepo) GetOrdersByFilter(context.Context, *models.OrderFilter) ([]models.FullEventInfo, error) {
+	return m.orders, nil
+}
+
+func TestListOrdersTimestamps(t *testing.T) {
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := &memoryOrderRepo{orders: []models.FullEventInfo{{
+		EventID:         uuid.New(),
+		OrderID:         uuid.New(),
+		UserID:          uuid.New(),
+		OrderType:       models.DefaultOrderType,
+		OrderStatusName: models.Chinazes,
+		UpdatedAt:       createdAt.Add(time.Minute),
+		CreatedAt:       createdAt,
+	}}}
+	o := NewOrderService(service.New(nil, repo, nil), nil)
+
+	res, err := o.ListOrders(context.Background(), &orderpb.ListOrdersRequest{Status: []string{models.Chinazes}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.GetOrders()) != 1 {
+		t.Fatalf("got %d orders, want 1", len(res.GetOrders()))
+	}
+
+	order := res.GetOrders()[0]
+	if order.GetUpdatedAt() != "2024-01-01T00:01:00Z" || order.GetCreatedAt() != "2024-01-01T00:00:00Z" {
+		t.Fatalf("timestamps are not RFC 3339: updated_at %q, created_at %q", order.GetUpdatedAt(), order.GetCreatedAt())
+	}
+	if order.GetEventId() != repo.orders[0].EventID.String() {
+		t.Fatalf("event_id %q, want %s", order.GetEventId(), repo.orders[0].EventID)
+	}
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// AdminAuth protects admin endpoints with a bearer token. If the token is
+// empty, every request is rejected.
+func AdminAuth(token string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reqToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || len(token) == 0 || subtle.ConstantTimeCompare([]byte(reqToken), []byte(token)) != 1 {
+				sendEmptyResponse(w, r, http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GetConnections returns the stream connection limits and their current usage.
+func (h *WebhookHandler) GetConnections(w http.ResponseWriter, r *http.Request) {
+	sendResponse(w, r, http.StatusOK, h.admission.stats())
+}
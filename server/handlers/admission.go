@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"sse/config"
+	"sse/models"
+)
+
+const (
+	// apiKeyHeader identifies the client for the per client limit. Clients
+	// without it are identified by their remote IP.
+	apiKeyHeader = "X-API-Key"
+
+	// forwardedForHeader holds the client IP set by the trusted proxies.
+	forwardedForHeader = "X-Forwarded-For"
+)
+
+// clientIdentity identifies the client of a stream connection.
+type clientIdentity struct {
+	key string // The hashed API key, or the IP if the client has no key
+	ip  string
+}
+
+// admission limits the number of concurrent stream connections.
+type admission struct {
+	mu        sync.Mutex
+	cfg       config.AdmissionConfig
+	total     int
+	perOrder  map[uuid.UUID]int
+	perClient map[string]int
+	perIP     map[string]int
+
+	apiKeys        map[string]bool // Hashed configured API keys
+	trustedProxies []*net.IPNet
+}
+
+func newAdmission(cfg config.AdmissionConfig) *admission {
+	a := &admission{
+		cfg:       cfg,
+		perOrder:  make(map[uuid.UUID]int),
+		perClient: make(map[string]int),
+		perIP:     make(map[string]int),
+		apiKeys:   make(map[string]bool, len(cfg.APIKeys)),
+	}
+
+	for _, apiKey := range cfg.APIKeys {
+		a.apiKeys[hashAPIKey(apiKey)] = true
+	}
+
+	for _, proxy := range cfg.TrustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			log.Printf("Ignoring trusted proxy %q: %v", proxy, err)
+			continue
+		}
+		a.trustedProxies = append(a.trustedProxies, ipNet)
+	}
+
+	return a
+}
+
+// identify returns the identity of a client connected from remoteAddr. The API key is
+// accepted only if it is configured, without configured keys it is ignored. The client IP
+// is taken from forwardedFor from right to left while the addresses belong to trusted proxies.
+func (a *admission) identify(apiKey, remoteAddr string, forwardedFor []string) (clientIdentity, error) {
+	ip, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		ip = remoteAddr
+	}
+
+	var hops []string
+	for _, value := range forwardedFor {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0 && a.trusted(ip); i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop.String()
+	}
+
+	id := clientIdentity{key: "ip:" + ip, ip: ip}
+	if len(apiKey) == 0 || len(a.apiKeys) == 0 {
+		return id, nil
+	}
+
+	hash := hashAPIKey(apiKey)
+	if !a.apiKeys[hash] {
+		return clientIdentity{}, models.ErrUnknownAPIKey
+	}
+	// Keys are hashed, so they are not exposed by the admin endpoints.
+	id.key = "key:" + hash[:12]
+
+	return id, nil
+}
+
+func (a *admission) trusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	for _, ipNet := range a.trustedProxies {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hashAPIKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// acquire admits a connection of the client to the order stream. orderID is
+// uuid.Nil for streams that are not bound to an order. The returned function
+// has to be called when the connection is closed.
+func (a *admission) acquire(orderID uuid.UUID, id clientIdentity) (func(), error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.cfg.MaxConnections > 0 && a.total >= a.cfg.MaxConnections {
+		return nil, models.ErrTooManyConnections
+	}
+	if a.cfg.MaxConnectionsPerOrder > 0 && orderID != uuid.Nil && a.perOrder[orderID] >= a.cfg.MaxConnectionsPerOrder {
+		return nil, models.ErrTooManyClientConnections
+	}
+	if a.cfg.MaxConnectionsPerClient > 0 && a.perClient[id.key] >= a.cfg.MaxConnectionsPerClient {
+		return nil, models.ErrTooManyClientConnections
+	}
+	if a.cfg.MaxConnectionsPerIP > 0 && a.perIP[id.ip] >= a.cfg.MaxConnectionsPerIP {
+		return nil, models.ErrTooManyClientConnections
+	}
+
+	a.total++
+	if orderID != uuid.Nil {
+		a.perOrder[orderID]++
+	}
+	a.perClient[id.key]++
+	a.perIP[id.ip]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			a.release(orderID, id)
+		})
+	}, nil
+}
+
+func (a *admission) release(orderID uuid.UUID, id clientIdentity) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.total--
+	if orderID != uuid.Nil {
+		a.releaseOrderLocked(orderID)
+	}
+	if a.perClient[id.key]--; a.perClient[id.key] <= 0 {
+		delete(a.perClient, id.key)
+	}
+	if a.perIP[id.ip]--; a.perIP[id.ip] <= 0 {
+		delete(a.perIP, id.ip)
+	}
+}
+
+// acquireOrder admits one more order subscription of an already admitted connection.
+// The returned function has to be called when the order is unsubscribed.
+func (a *admission) acquireOrder(orderID uuid.UUID) (func(), error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.cfg.MaxConnectionsPerOrder > 0 && a.perOrder[orderID] >= a.cfg.MaxConnectionsPerOrder {
+		return nil, models.ErrTooManyClientConnections
+	}
+	a.perOrder[orderID]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+
+			a.releaseOrderLocked(orderID)
+		})
+	}, nil
+}
+
+func (a *admission) releaseOrderLocked(orderID uuid.UUID) {
+	if a.perOrder[orderID]--; a.perOrder[orderID] <= 0 {
+		delete(a.perOrder, orderID)
+	}
+}
+
+// ConnectionStats is the current usage of the connection limits.
+type ConnectionStats struct {
+	Limits    config.AdmissionConfig `json:"limits"`
+	Total     int                    `json:"total"`
+	PerOrder  map[uuid.UUID]int      `json:"per_order"`
+	PerClient map[string]int         `json:"per_client"`
+	PerIP     map[string]int         `json:"per_ip"`
+}
+
+func (a *admission) stats() ConnectionStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	res := ConnectionStats{
+		Limits:    a.cfg,
+		Total:     a.total,
+		PerOrder:  make(map[uuid.UUID]int, len(a.perOrder)),
+		PerClient: make(map[string]int, len(a.perClient)),
+		PerIP:     make(map[string]int, len(a.perIP)),
+	}
+	// The configured keys are secrets.
+	res.Limits.APIKeys = nil
+
+	for orderID, n := range a.perOrder {
+		res.PerOrder[orderID] = n
+	}
+	for clientKey, n := range a.perClient {
+		res.PerClient[clientKey] = n
+	}
+	for ip, n := range a.perIP {
+		res.PerIP[ip] = n
+	}
+
+	return res
+}
+
+// admit checks the connection limits for the request. If the connection
+// is not admitted, the error response is already sent.
+func (h *WebhookHandler) admit(w http.ResponseWriter, r *http.Request, orderID uuid.UUID) (func(), bool) {
+	id, err := h.requestIdentity(r)
+	if err != nil {
+		sendResponse(w, r, http.StatusUnauthorized, map[string]string{"message": err.Error()})
+		return nil, false
+	}
+
+	release, err := h.admission.acquire(orderID, id)
+	if err != nil {
+		h.sendAdmissionError(w, r, err)
+		return nil, false
+	}
+
+	return release, true
+}
+
+// sendAdmissionError responds to a connection that is over the limits.
+func (h *WebhookHandler) sendAdmissionError(w http.ResponseWriter, r *http.Request, err error) {
+	if retryAfter := h.cfg.Admission.RetryAfter.Std(); retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+	}
+
+	if errors.Is(err, models.ErrTooManyConnections) {
+		sendResponse(w, r, http.StatusServiceUnavailable, map[string]string{"message": err.Error()})
+	} else {
+		sendResponse(w, r, http.StatusTooManyRequests, map[string]string{"message": err.Error()})
+	}
+}
+
+func (h *WebhookHandler) requestIdentity(r *http.Request) (clientIdentity, error) {
+	return h.admission.identify(r.Header.Get(apiKeyHeader), r.RemoteAddr, r.Header.Values(forwardedForHeader))
+}
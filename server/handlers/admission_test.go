@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+
+	"sse/broker"
+	"sse/config"
+	"sse/models"
+	"sse/service"
+)
+
+func TestAdmissionIdentify(t *testing.T) {
+	a := newAdmission(config.AdmissionConfig{
+		APIKeys:        []string{"secret"},
+		TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1"},
+	})
+
+	tests := []struct {
+		name         string
+		apiKey       string
+		remoteAddr   string
+		forwardedFor []string
+		wantIP       string
+		wantKey      bool
+		wantErr      error
+	}{
+		{name: "remote ip", remoteAddr: "203.0.113.7:1234", wantIP: "203.0.113.7"},
+		{
+			name: "forwarded by untrusted client", remoteAddr: "203.0.113.7:1234",
+			forwardedFor: []string{"198.51.100.1"}, wantIP: "203.0.113.7",
+		},
+		{
+			name: "forwarded by trusted proxy", remoteAddr: "10.1.2.3:1234",
+			forwardedFor: []string{"198.51.100.1"}, wantIP: "198.51.100.1",
+		},
+		{
+			name: "spoofed hop before the client", remoteAddr: "10.1.2.3:1234",
+			forwardedFor: []string{"1.1.1.1, 198.51.100.1, 192.168.1.1"}, wantIP: "198.51.100.1",
+		},
+		{
+			name: "hops in several headers", remoteAddr: "10.1.2.3:1234",
+			forwardedFor: []string{"198.51.100.1", "10.9.9.9"}, wantIP: "198.51.100.1",
+		},
+		{
+			name: "malformed hop", remoteAddr: "10.1.2.3:1234",
+			forwardedFor: []string{"198.51.100.1, junk"}, wantIP: "10.1.2.3",
+		},
+		{name: "configured key", apiKey: "secret", remoteAddr: "203.0.113.7:1234", wantIP: "203.0.113.7", wantKey: true},
+		{name: "unknown key", apiKey: "guess", remoteAddr: "203.0.113.7:1234", wantErr: models.ErrUnknownAPIKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := a.identify(tt.apiKey, tt.remoteAddr, tt.forwardedFor)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if id.ip != tt.wantIP {
+				t.Errorf("ip %s, want %s", id.ip, tt.wantIP)
+			}
+			if wantKey := "ip:" + tt.wantIP; tt.wantKey == (id.key == wantKey) {
+				t.Errorf("key %s, api key expected: %v", id.key, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestAdmissionIgnoresKeysWithoutConfig(t *testing.T) {
+	a := newAdmission(config.AdmissionConfig{})
+
+	id, err := a.identify("anything", "203.0.113.7:1234", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.key != "ip:203.0.113.7" {
+		t.Fatalf("key %s, want the ip", id.key)
+	}
+}
+
+// Clients with different keys from one IP share the per IP limit.
+func TestAdmissionPerIPLimit(t *testing.T) {
+	a := newAdmission(config.AdmissionConfig{
+		MaxConnectionsPerClient: 1,
+		MaxConnectionsPerIP:     2,
+		APIKeys:                 []string{"a", "b", "c"},
+	})
+
+	acquire := func(apiKey string) error {
+		id, err := a.identify(apiKey, "203.0.113.7:1234", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = a.acquire(uuid.New(), id)
+		return err
+	}
+
+	if err := acquire("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := acquire("a"); !errors.Is(err, models.ErrTooManyClientConnections) {
+		t.Fatalf("second connection with the same key: %v", err)
+	}
+	if err := acquire("b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := acquire("c"); !errors.Is(err, models.ErrTooManyClientConnections) {
+		t.Fatalf("third connection from the ip: %v", err)
+	}
+}
+
+// Orders subscribed over a WebSocket count against the per order limit and the per socket cap.
+func TestWebSocketSubscriptionAdmission(t *testing.T) {
+	h := NewWebhookHandler(
+		service.New(&memoryWebhookRepo{}, nil, nil),
+		broker.NewMemory(config.SlowConsumerConfig{Policy: config.SlowConsumerDisconnect, Buffer: 5}),
+		config.SSEConfig{Admission: config.AdmissionConfig{MaxConnectionsPerOrder: 1, MaxOrdersPerSocket: 2}},
+	)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/orders/{order_id}/ws", h.StreamWS)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	dial := func(orderID uuid.UUID) (*websocket.Conn, *http.Response, error) {
+		return websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/orders/"+orderID.String()+"/ws", nil)
+	}
+	command := func(conn *websocket.Conn, action string, orderID uuid.UUID) wsFrame {
+		t.Helper()
+
+		if err := conn.WriteJSON(wsCommand{Action: action, OrderID: orderID.String()}); err != nil {
+			t.Fatal(err)
+		}
+		var frame wsFrame
+		if err := conn.ReadJSON(&frame); err != nil {
+			t.Fatal(err)
+		}
+		return frame
+	}
+
+	first, second, third := uuid.New(), uuid.New(), uuid.New()
+
+	conn, _, err := dial(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var subscribed wsFrame
+	if err = conn.ReadJSON(&subscribed); err != nil || subscribed.Event != wsEventSubscribed {
+		t.Fatalf("subscribe to the first order: %+v, %v", subscribed, err)
+	}
+
+	if frame := command(conn, wsActionSubscribe, second); frame.Event != wsEventSubscribed {
+		t.Fatalf("subscribe to the second order: %+v", frame)
+	}
+	if frame := command(conn, wsActionSubscribe, third); frame.Event != wsEventError {
+		t.Fatalf("subscribe over the per socket cap: %+v", frame)
+	}
+
+	if _, resp, err := dial(second); err == nil || resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("connect to an order over the per order limit: %v", err)
+	}
+
+	if frame := command(conn, wsActionUnsubscribe, second); frame.Event != wsEventUnsubscribed {
+		t.Fatalf("unsubscribe from the second order: %+v", frame)
+	}
+
+	other, _, err := dial(second)
+	if err != nil {
+		t.Fatalf("connect to an unsubscribed order: %v", err)
+	}
+	other.Close()
+}
+
+// historyFailingRepo fails to load the history of one order.
+type historyFailingRepo struct {
+	*memoryWebhookRepo
+	failing uuid.UUID
+}
+
+func (f *historyFailingRepo) GetOrderEvents(ctx context.Context, orderID uuid.UUID) ([]models.FullEventInfo, error) {
+	if orderID == f.failing {
+		return nil, errors.New("history unavailable")
+	}
+
+	return f.memoryWebhookRepo.GetOrderEvents(ctx, orderID)
+}
+
+// An order whose history can't be loaded is not left subscribed and doesn't hold its per order slot.
+func TestWebSocketSubscribeHistoryError(t *testing.T) {
+	repo := &historyFailingRepo{memoryWebhookRepo: &memoryWebhookRepo{}, failing: uuid.New()}
+	h := NewWebhookHandler(
+		service.New(repo, nil, nil),
+		broker.NewMemory(config.SlowConsumerConfig{Policy: config.SlowConsumerDisconnect, Buffer: 5}),
+		config.SSEConfig{},
+	)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/orders/{order_id}/ws", h.StreamWS)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/orders/"+uuid.NewString()+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var frame wsFrame
+	if err = conn.ReadJSON(&frame); err != nil || frame.Event != wsEventSubscribed {
+		t.Fatalf("subscribe to the first order: %+v, %v", frame, err)
+	}
+
+	if err = conn.WriteJSON(wsCommand{Action: wsActionSubscribe, OrderID: repo.failing.String()}); err != nil {
+		t.Fatal(err)
+	}
+	if err = conn.ReadJSON(&frame); err != nil || frame.Event != wsEventError {
+		t.Fatalf("subscribe to the order without history: %+v, %v", frame, err)
+	}
+
+	if n := h.admission.stats().PerOrder[repo.failing]; n != 0 {
+		t.Fatalf("the order holds %d per order slots", n)
+	}
+	if subs := h.subscribers(repo.failing); len(subs) != 0 {
+		t.Fatal("the order topic is still subscribed")
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"sse/models"
+)
+
+// maxBatchItems limits the events of a batch webhook.
+const maxBatchItems = 1000
+
+// BroadcastBatch stores a batch of webhook events sent as a JSON array or as
+// NDJSON. The events are processed in order like BroadcastMessage and the
+// response has a result for every item, a rejected item doesn't fail the batch.
+func (h *WebhookHandler) BroadcastBatch(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		SendBadRequest(w, r, err)
+		return
+	}
+
+	items, err := splitBatch(body)
+	if err != nil {
+		SendBadRequest(w, r, err)
+		return
+	}
+
+	if len(items) > maxBatchItems {
+		SendBadRequest(w, r, fmt.Errorf("%w: more than %d items", models.ErrBadRequest, maxBatchItems))
+		return
+	}
+
+	resp := models.BatchResponse{Results: make([]models.BatchItemResult, 0, len(items))}
+	for i, item := range items {
+		result := h.addBatchItem(r, item)
+		result.Index = i
+		resp.Results = append(resp.Results, result)
+	}
+
+	sendResponse(w, r, http.StatusOK, resp)
+}
+
+func (h *WebhookHandler) addBatchItem(r *http.Request, item json.RawMessage) models.BatchItemResult {
+	var req models.EventBody
+	if err := json.Unmarshal(item, &req); err != nil {
+		return models.BatchItemResult{Result: models.BatchInvalid, Message: err.Error()}
+	}
+
+	result := models.BatchItemResult{EventID: req.EventID}
+
+	event, err := h.ValidateEventReq(req)
+	if err != nil {
+		result.Result = models.BatchInvalid
+		result.Message = err.Error()
+		return result
+	}
+
+	// Stored events are published to the broker of every instance through the database.
+	err = h.service.AddEvent(r.Context(), event, req.OrderStatus)
+	switch {
+	case err == nil:
+		result.Result = models.BatchAccepted
+		return result
+	case errors.Is(err, models.ErrAlreadyProcessed):
+		result.Result = models.BatchDuplicate
+	case errors.Is(err, models.ErrAlreadyExistsFinalStatus):
+		result.Result = models.BatchFinalStatus
+	case errors.Is(err, models.ErrTransitionWindowExpired):
+		result.Result = models.BatchWindowExpired
+	case errors.Is(err, models.ErrBadRequest), errors.Is(err, models.ErrInvalidTransition):
+		result.Result = models.BatchInvalid
+	default:
+		result.Result = models.BatchError
+	}
+	result.Message = err.Error()
+
+	return result
+}
+
+// splitBatch returns the items of a JSON array or of NDJSON, one item per non-empty line.
+func splitBatch(body []byte) ([]json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return nil, models.ErrBadRequest
+	}
+
+	var items []json.RawMessage
+	if trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, err
+		}
+
+		return items, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	scanner.Buffer(nil, maxWebhookBody)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) != 0 {
+			items = append(items, json.RawMessage(bytes.Clone(line)))
+		}
+	}
+
+	return items, scanner.Err()
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"sse/models"
+	"sse/workflow"
+)
+
+func TestBroadcastBatchResults(t *testing.T) {
+	repo := &memoryWebhookRepo{}
+	h := newTestWebhookHandler(repo)
+
+	orderID, userID := uuid.New(), uuid.New()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	repo.add(orderID, models.CoolOrderCreated, start)
+	repo.add(orderID, models.SBUVarificationPending, start.Add(time.Second))
+	repo.add(orderID, models.ConfirmedByMayor, start.Add(2*time.Second))
+	chinazes := repo.add(orderID, models.Chinazes, start.Add(3*time.Second))
+
+	item := func(eventID uuid.UUID, status string, updatedAt time.Time) models.EventBody {
+		return models.EventBody{
+			EventID:     eventID.String(),
+			OrderID:     orderID.String(),
+			UserID:      userID.String(),
+			OrderStatus: status,
+			UpdatedAt:   updatedAt.Format(models.TimeFormat),
+			CreatedAt:   start.Format(models.TimeFormat),
+		}
+	}
+	refundDeadline := chinazes.UpdatedAt.Add(workflow.GiveMyMoneyBackTimeout)
+
+	tests := []struct {
+		name string
+		item models.EventBody
+		want string
+	}{
+		{"duplicate", item(chinazes.EventID, models.Chinazes, chinazes.UpdatedAt), models.BatchDuplicate},
+		{"late refund", item(uuid.New(), models.GiveMyMoneyBack, refundDeadline.Add(time.Second)), models.BatchWindowExpired},
+		{"unknown status", item(uuid.New(), "teleported", refundDeadline), models.BatchInvalid},
+		{"final status conflict", item(uuid.New(), models.Failed, refundDeadline), models.BatchFinalStatus},
+		{"refund in the window", item(uuid.New(), models.GiveMyMoneyBack, refundDeadline), models.BatchAccepted},
+	}
+
+	items := make([]models.EventBody, 0, len(tests))
+	for _, tt := range tests {
+		items = append(items, tt.item)
+	}
+	body, err := json.Marshal(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	h.BroadcastBatch(w, httptest.NewRequest(http.MethodPost, "/webhooks/payments/orders:batch", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp models.BatchResponse
+	if err = json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != len(tests) {
+		t.Fatalf("got %d results, want %d", len(resp.Results), len(tests))
+	}
+
+	for i, tt := range tests {
+		if got := resp.Results[i]; got.Index != i || got.Result != tt.want {
+			t.Errorf("%s: got %+v, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -9,7 +9,7 @@ import (
 )
 
 func sendEmptyResponse(w http.ResponseWriter, r *http.Request, statusCode int) {
-	log.Println(
+	log.Printf(
 		"resp %s: %s -%d",
 		r.Method,
 		r.RequestURI,
@@ -21,7 +21,7 @@ func sendEmptyResponse(w http.ResponseWriter, r *http.Request, statusCode int) {
 }
 
 func sendResponse(w http.ResponseWriter, r *http.Request, statusCode int, resp interface{}) {
-	log.Println(
+	log.Printf(
 		"resp %s: %s - %d - %v",
 		r.Method,
 		r.RequestURI,
@@ -70,8 +70,17 @@ func SendHTTPError(w http.ResponseWriter, r *http.Request, err error) {
 	switch {
 	case errors.Is(err, models.ErrAlreadyExistsFinalStatus):
 		SendGone(w, r)
-	case errors.Is(err, models.ErrAlreadyProcessed):
+	case errors.Is(err, models.ErrTransitionWindowExpired):
+		sendResponse(w, r, http.StatusConflict, map[string]string{
+			"code":    models.CodeTransitionWindowExpired,
+			"message": err.Error(),
+		})
+	case errors.Is(err, models.ErrAlreadyProcessed), errors.Is(err, models.ErrAlreadyExists):
 		SendConflict(w, r)
+	case errors.Is(err, models.ErrBadRequest):
+		SendBadRequest(w, r, err)
+	case errors.Is(err, models.ErrInvalidTransition):
+		SendBadRequest(w, r, err)
 	default:
 		SendInternalServerError(w, r, err)
 	}
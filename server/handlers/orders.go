@@ -34,32 +34,58 @@ func (h *OrdersHandler) GetOrdersByFilter(w http.ResponseWriter, r *http.Request
 	sendResponse(w, r, http.StatusOK, res)
 }
 
+// parseOrdersFilters reads the orders list filter, it is validated by models.OrderFilter.Validate
+// the same way as the gRPC ListOrders request.
 func parseOrdersFilters(r *http.Request) (*models.OrderFilter, error) {
+	filter, err := parseStreamFilters(r)
+	if err != nil {
+		return nil, err
+	}
+
+	limitStr := r.URL.Query().Get("limit")
+	offsetStr := r.URL.Query().Get("offset")
+
+	if len(limitStr) != 0 {
+		filter.Limit, err = strconv.Atoi(limitStr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(offsetStr) != 0 {
+		filter.Offset, err = strconv.Atoi(offsetStr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	filter.SortBy = r.URL.Query().Get("sort_by")
+	filter.SortOrder = r.URL.Query().Get("sort_order")
+
+	if err = filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+// parseStreamFilters reads the status, order_type, is_final and user_id filters of the events stream.
+// Unlike the orders list all of them are optional.
+func parseStreamFilters(r *http.Request) (*models.OrderFilter, error) {
 	var (
-		statuses   []string
-		isFinalPtr *bool
-		userID     uuid.UUID
-		limit      int
-		offset     int
-		sortBy     string
-		sortOrder  string
-
-		err error
+		filter models.OrderFilter
+		err    error
 	)
 	statusesStr := r.URL.Query().Get("status")
 	isFinalStr := r.URL.Query().Get("is_final")
 	userIDStr := r.URL.Query().Get("user_id")
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
-	sortByStr := r.URL.Query().Get("sort_by")
-	sortOrderStr := r.URL.Query().Get("sort_order")
 
-	if (len(statusesStr) == 0 && len(isFinalStr) == 0) ||
-		(len(statusesStr) != 0 && len(isFinalStr) != 0) {
-		return nil, models.ErrBadRequest
+	filter.Status, err = makeStringSlice(statusesStr)
+	if err != nil {
+		return nil, err
 	}
 
-	statuses, err = makeStringSlice(statusesStr)
+	filter.OrderType, err = makeStringSlice(r.URL.Query().Get("order_type"))
 	if err != nil {
 		return nil, err
 	}
@@ -69,63 +95,17 @@ func parseOrdersFilters(r *http.Request) (*models.OrderFilter, error) {
 		if err != nil {
 			return nil, err
 		}
-		isFinalPtr = &isFinal
-	} else {
-		isFinalPtr = nil
+		filter.IsFinal = &isFinal
 	}
 
 	if len(userIDStr) != 0 {
-		userID, err = uuid.Parse(userIDStr)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	if len(limitStr) != 0 {
-		limit, err = strconv.Atoi(limitStr)
+		filter.UserID, err = uuid.Parse(userIDStr)
 		if err != nil {
 			return nil, err
 		}
-	} else {
-		limit = 10
-	}
-
-	if len(offsetStr) != 0 {
-		offset, err = strconv.Atoi(offsetStr)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		offset = 0
-	}
-
-	if len(sortByStr) != 0 {
-		if sortByStr != models.SortByCreatedAt && sortByStr != models.SortByUpdatedAt {
-			return nil, models.ErrBadRequest
-		}
-		sortBy = sortByStr
-	} else {
-		sortBy = models.SortByCreatedAt
-	}
-
-	if len(sortOrderStr) != 0 {
-		if sortOrderStr != models.OrderASC && sortOrderStr != models.OrderDESC {
-			return nil, models.ErrBadRequest
-		}
-		sortOrder = sortOrderStr
-	} else {
-		sortOrder = models.OrderDESC
 	}
 
-	return &models.OrderFilter{
-		Status:    statuses,
-		UserID:    userID,
-		Limit:     limit,
-		Offset:    offset,
-		IsFinal:   isFinalPtr,
-		SortBy:    sortBy,
-		SortOrder: sortOrder,
-	}, nil
+	return &filter, nil
 }
 
 func makeStringSlice(input string) ([]string, error) {
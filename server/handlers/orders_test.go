@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sse/models"
+)
+
+func TestParseOrdersFilters(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+		want    models.OrderFilter
+	}{
+		{query: "status=chinazes", want: models.OrderFilter{
+			Status: []string{models.Chinazes}, Limit: 10, SortBy: models.SortByCreatedAt, SortOrder: models.OrderDESC,
+		}},
+		{query: "is_final=true&limit=5&offset=10&sort_by=updated_at&sort_order=ASC", want: models.OrderFilter{
+			Limit: 5, Offset: 10, SortBy: models.SortByUpdatedAt, SortOrder: models.OrderASC,
+		}},
+		{query: "", wantErr: true},
+		{query: "status=chinazes&is_final=true", wantErr: true},
+		{query: "status=chinazes&limit=-1", wantErr: true},
+		{query: "status=chinazes&offset=-1", wantErr: true},
+		{query: "status=chinazes&sort_by=name", wantErr: true},
+		{query: "status=chinazes&sort_order=up", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			filter, err := parseOrdersFilters(httptest.NewRequest(http.MethodGet, "/orders?"+tt.query, nil))
+			if tt.wantErr {
+				if !errors.Is(err, models.ErrBadRequest) {
+					t.Fatalf("got %v, want a bad request", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(filter.Status) != len(tt.want.Status) || filter.Limit != tt.want.Limit || filter.Offset != tt.want.Offset ||
+				filter.SortBy != tt.want.SortBy || filter.SortOrder != tt.want.SortOrder {
+				t.Fatalf("got %+v, want %+v", *filter, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"sse/broker"
+	"sse/models"
+)
+
+const (
+	defaultPollWait = 30 * time.Second
+	maxPollWait     = 1 * time.Minute
+)
+
+// collectWriter collects events instead of sending them, so a poll request
+// gets events in the same order as a stream.
+type collectWriter struct {
+	events []models.EventMsg
+}
+
+func (c *collectWriter) writeEvent(eventMsg *models.EventMsg) error {
+	c.events = append(c.events, *eventMsg)
+	return nil
+}
+
+func (c *collectWriter) writeGap(gap *models.Gap) error {
+	c.events = append(c.events, *gapEventMsg(gap))
+	return nil
+}
+
+// Poll returns the order events after the `after` cursor. If there are none,
+// it waits up to `wait` for a new event before returning an empty list. When the
+// order can no longer change, the page is marked as ended and returned without waiting.
+func (h *WebhookHandler) Poll(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(mux.Vars(r)["order_id"])
+	if err != nil {
+		SendBadRequest(w, r, err)
+		return
+	}
+
+	after, wait, err := parsePollParams(r)
+	if err != nil {
+		SendBadRequest(w, r, err)
+		return
+	}
+
+	release, ok := h.admit(w, r, orderID)
+	if !ok {
+		return
+	}
+	defer release()
+
+	client, err := h.subscribe(r.Context(), transportPoll, r.RemoteAddr, broker.OrderTopic(orderID))
+	if err != nil {
+		SendHTTPError(w, r, err)
+		return
+	}
+	defer h.unsubscribe(client)
+
+	historyEvents, err := h.orderHistory(r.Context(), client, orderID, after, historyParams{})
+	if err != nil {
+		SendHTTPError(w, r, err)
+		return
+	}
+
+	out := &collectWriter{}
+	if err = h.sendMsg(out, historyEvents, client); err != nil {
+		SendHTTPError(w, r, err)
+		return
+	}
+
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+
+	endTimeout := h.endTimeout(client, orderID)
+	gapTimeout := h.gapTimeout(client)
+
+	for expired := false; len(out.events) == 0 && !expired; {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-client.sub.Done():
+			expired = true
+
+		case <-client.sub.Ready():
+			if err = h.sendQueuedMsg(out, client); err != nil {
+				SendHTTPError(w, r, err)
+				return
+			}
+
+			endTimeout = h.endTimeout(client, orderID)
+			gapTimeout = h.gapTimeout(client)
+
+		case <-gapTimeout:
+			if err = h.flushGaps(out, client); err != nil {
+				SendHTTPError(w, r, err)
+				return
+			}
+
+			endTimeout = h.endTimeout(client, orderID)
+			gapTimeout = h.gapTimeout(client)
+
+		case <-endTimeout:
+			expired = true
+
+		case <-timeout.C:
+			expired = true
+		}
+	}
+
+	endsAt, ended := client.order(orderID).endsAt()
+
+	cursor := ""
+	if after != uuid.Nil {
+		cursor = after.String()
+	}
+	if len(out.events) != 0 {
+		cursor = out.events[len(out.events)-1].EventID.String()
+	}
+
+	sendResponse(w, r, http.StatusOK, models.EventsPage{
+		Events: out.events,
+		Cursor: cursor,
+		Ended:  ended && !time.Now().Before(endsAt),
+	})
+}
+
+func parsePollParams(r *http.Request) (uuid.UUID, time.Duration, error) {
+	var (
+		after uuid.UUID
+		wait  = defaultPollWait
+		err   error
+	)
+	afterStr := r.URL.Query().Get("after")
+	waitStr := r.URL.Query().Get("wait")
+
+	if len(afterStr) != 0 {
+		after, err = uuid.Parse(afterStr)
+		if err != nil {
+			return uuid.Nil, 0, err
+		}
+	}
+
+	if len(waitStr) != 0 {
+		wait, err = time.ParseDuration(waitStr)
+		if err != nil {
+			return uuid.Nil, 0, err
+		}
+		if wait < 0 || wait > maxPollWait {
+			return uuid.Nil, 0, models.ErrBadRequest
+		}
+	}
+
+	return after, wait, nil
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"sse/broker"
+)
+
+// OrderStream is what was sent to a client for a single order.
+type OrderStream struct {
+	LastSentStatus string `json:"last_sent_status"`
+	Unsent         int    `json:"unsent"` // Events waiting in the reorder buffer for the previous status
+}
+
+// SubscriptionInfo describes a connected stream client.
+type SubscriptionInfo struct {
+	ID          uuid.UUID                 `json:"id"`
+	Transport   string                    `json:"transport"`
+	RemoteAddr  string                    `json:"remote_addr"`
+	ConnectedAt time.Time                 `json:"connected_at"`
+	Orders      map[uuid.UUID]OrderStream `json:"orders"`
+}
+
+// updateStats copies the orders state for the admin endpoints. It is called by
+// the goroutine that sends events to the client after the state changes.
+func (c *clientState) updateStats() {
+	stats := make(map[uuid.UUID]OrderStream, len(c.orders))
+	for orderID, state := range c.orders {
+		var lastSentStatus string
+		if state.lastSentMessage != nil {
+			lastSentStatus = state.lastSentMessage.OrderStatus
+		}
+		stats[orderID] = OrderStream{
+			LastSentStatus: lastSentStatus,
+			Unsent:         len(state.unsentMsg),
+		}
+	}
+
+	c.statsMutex.Lock()
+	c.stats = stats
+	c.statsMutex.Unlock()
+}
+
+func (c *clientState) info() SubscriptionInfo {
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+
+	return SubscriptionInfo{
+		ID:          c.sub.ID(),
+		Transport:   c.transport,
+		RemoteAddr:  c.remoteAddr,
+		ConnectedAt: c.connectedAt,
+		Orders:      c.stats,
+	}
+}
+
+// subscribers returns the connected clients. If orderID is not uuid.Nil,
+// only clients subscribed to the order are returned.
+func (h *WebhookHandler) subscribers(orderID uuid.UUID) []*clientState {
+	var ids []uuid.UUID
+	if orderID != uuid.Nil {
+		ids = h.broker.Subscribers(broker.OrderTopic(orderID))
+	}
+
+	h.clientsMutex.Lock()
+	defer h.clientsMutex.Unlock()
+
+	var res []*clientState
+	if orderID != uuid.Nil {
+		for _, id := range ids {
+			if client, ok := h.clients[id]; ok {
+				res = append(res, client)
+			}
+		}
+		return res
+	}
+
+	for _, client := range h.clients {
+		res = append(res, client)
+	}
+
+	return res
+}
+
+func subscriptionsInfo(clients []*clientState) []SubscriptionInfo {
+	res := make([]SubscriptionInfo, 0, len(clients))
+	for _, client := range clients {
+		res = append(res, client.info())
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ConnectedAt.Before(res[j].ConnectedAt)
+	})
+
+	return res
+}
+
+// GetSubscriptions lists all connected stream clients.
+func (h *WebhookHandler) GetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	sendResponse(w, r, http.StatusOK, subscriptionsInfo(h.subscribers(uuid.Nil)))
+}
+
+// GetOrderSubscriptions lists stream clients subscribed to the order.
+func (h *WebhookHandler) GetOrderSubscriptions(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(mux.Vars(r)["order_id"])
+	if err != nil {
+		SendBadRequest(w, r, err)
+		return
+	}
+
+	sendResponse(w, r, http.StatusOK, subscriptionsInfo(h.subscribers(orderID)))
+}
+
+// DisconnectSubscription closes the connection of a stream client.
+func (h *WebhookHandler) DisconnectSubscription(w http.ResponseWriter, r *http.Request) {
+	subscriptionID, err := uuid.Parse(mux.Vars(r)["subscription_id"])
+	if err != nil {
+		SendBadRequest(w, r, err)
+		return
+	}
+
+	if !h.broker.Disconnect(subscriptionID) {
+		sendEmptyResponse(w, r, http.StatusNotFound)
+		return
+	}
+
+	sendEmptyResponse(w, r, http.StatusNoContent)
+}
+
+// DisconnectOrderSubscriptions closes the connections of all stream clients subscribed to the order.
+func (h *WebhookHandler) DisconnectOrderSubscriptions(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(mux.Vars(r)["order_id"])
+	if err != nil {
+		SendBadRequest(w, r, err)
+		return
+	}
+
+	var disconnected int
+	for _, subscriptionID := range h.broker.Subscribers(broker.OrderTopic(orderID)) {
+		if h.broker.Disconnect(subscriptionID) {
+			disconnected++
+		}
+	}
+
+	sendResponse(w, r, http.StatusOK, map[string]int{"disconnected": disconnected})
+}
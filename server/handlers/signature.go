@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"sse/config"
+	"sse/signature"
+)
+
+// maxWebhookBody limits the webhook body read before the signature is verified.
+const maxWebhookBody = 10 << 20
+
+// WebhookSignature verifies the signature header of webhook requests against the
+// raw body before it is decoded. If no secrets are configured, webhooks are not verified
+// and a warning is logged when the middleware is created.
+func WebhookSignature(cfg config.WebhookConfig) mux.MiddlewareFunc {
+	if len(cfg.Secrets) == 0 {
+		log.Println("webhook.secrets is not set, webhook signatures are not verified")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(cfg.Secrets) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+			if err != nil {
+				SendBadRequest(w, r, err)
+				return
+			}
+
+			err = signature.Verify(r.Header.Get(signature.Header), body, cfg.Secrets, cfg.Tolerance.Std(), time.Now())
+			if err != nil {
+				sendResponse(w, r, http.StatusUnauthorized, map[string]string{"message": err.Error()})
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"sse/models"
+)
+
+// eventWriter sends events to a client over a particular transport.
+type eventWriter interface {
+	writeEvent(eventMsg *models.EventMsg) error
+	writeGap(gap *models.Gap) error
+}
+
+// eventWriterFunc adapts a function to an eventWriter. Gaps are passed to
+// the function as events, see gapEventMsg.
+type eventWriterFunc func(eventMsg *models.EventMsg) error
+
+func (f eventWriterFunc) writeEvent(eventMsg *models.EventMsg) error {
+	return f(eventMsg)
+}
+
+func (f eventWriterFunc) writeGap(gap *models.Gap) error {
+	return f(gapEventMsg(gap))
+}
+
+// gapEventMsg represents the gap as an event for transports that can only send
+// events. It has the gap event type as the order status and no event id.
+func gapEventMsg(gap *models.Gap) *models.EventMsg {
+	return &models.EventMsg{
+		OrderID:     gap.OrderID,
+		UserID:      gap.UserID,
+		OrderStatus: models.EventGap,
+	}
+}
+
+// sseWriter sends events as text/event-stream frames.
+type sseWriter struct {
+	w       io.Writer
+	flusher http.Flusher
+}
+
+func (s sseWriter) writeEvent(eventMsg *models.EventMsg) error {
+	return writeEvent(s.w, s.flusher, eventMsg)
+}
+
+func (s sseWriter) writeGap(gap *models.Gap) error {
+	return writeGap(s.w, s.flusher, gap)
+}
+
+// writeEvent encodes the event as a text/event-stream frame. The event id is
+// used as the frame id and the order status as the event type, so clients can
+// subscribe to particular statuses with addEventListener.
+func writeEvent(w io.Writer, flusher http.Flusher, eventMsg *models.EventMsg) error {
+	data, err := json.Marshal(eventMsg)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", eventMsg.EventID, eventMsg.OrderStatus, data)
+	if err != nil {
+		return err
+	}
+	flusher.Flush()
+
+	return nil
+}
+
+// writeGap encodes the gap as a text/event-stream frame. The frame has no id,
+// so a reconnecting client resumes after the last event it has received.
+func writeGap(w io.Writer, flusher http.Flusher, gap *models.Gap) error {
+	data, err := json.Marshal(gap)
+	if err != nil {
+		return err
+	}
+
+	if _, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", models.EventGap, data); err != nil {
+		return err
+	}
+	flusher.Flush()
+
+	return nil
+}
+
+// writeRetry tells the client how long to wait before reconnecting.
+func writeRetry(w io.Writer, flusher http.Flusher, retry time.Duration) error {
+	if retry <= 0 {
+		return nil
+	}
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", retry.Milliseconds()); err != nil {
+		return err
+	}
+	flusher.Flush()
+
+	return nil
+}
+
+// writeComment sends a comment line. Clients ignore it, but it keeps
+// proxies and load balancers from closing an idle connection.
+func writeComment(w io.Writer, flusher http.Flusher, comment string) error {
+	if _, err := fmt.Fprintf(w, ": %s\n\n", comment); err != nil {
+		return err
+	}
+	flusher.Flush()
+
+	return nil
+}
+
+// writeEnd tells the client that the order can no longer change and the stream is closed.
+func writeEnd(w io.Writer, flusher http.Flusher, lastEventMsg *models.EventMsg) error {
+	data, err := json.Marshal(endOfStream(lastEventMsg))
+	if err != nil {
+		return err
+	}
+
+	if _, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", models.EventEnd, data); err != nil {
+		return err
+	}
+	flusher.Flush()
+
+	return nil
+}
+
+func endOfStream(lastEventMsg *models.EventMsg) map[string]string {
+	return map[string]string{
+		"order_id":     lastEventMsg.OrderID.String(),
+		"order_status": lastEventMsg.OrderStatus,
+	}
+}
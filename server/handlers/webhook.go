@@ -1,99 +1,146 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
+	"slices"
 	"sort"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 
+	"sse/broker"
+	"sse/config"
 	"sse/models"
 	"sse/service"
+	"sse/workflow"
 )
 
-type clientState struct {
-	messageChan     chan []byte
+// orderState keeps track of what was already sent to a client for a single order.
+type orderState struct {
 	lastSentMessage *models.EventMsg
 	unsentMsg       []*models.EventMsg
+	gapSince        time.Time // When unsentMsg started waiting for the previous order status
+	historySkipped  bool      // The first sent event is not preceded by the order history
 }
 
-type WebhookHandler struct {
-	service *service.Service
+const (
+	historyNone   = "none"
+	historyLatest = "latest"
+	historyAll    = "all"
+)
+
+const (
+	transportSSE       = "sse"
+	transportWebSocket = "websocket"
+	transportPoll      = "poll"
+	transportGRPC      = "grpc"
+)
 
-	Notifier       chan []byte                         // Events are pushed to this channel by the main events-gathering routine
-	newClients     chan map[uuid.UUID]chan []byte      // New client connections are pushed to this channel
-	closingClients chan map[uuid.UUID]chan []byte      // Closed client connections are pushed to this channel
-	clients        map[uuid.UUID]map[*clientState]bool // Client connections registry
-	clientsMutex   sync.Mutex                          // Mutex to protect access to clients map
+type clientState struct {
+	sub         broker.Subscription
+	transport   string
+	remoteAddr  string
+	connectedAt time.Time
+
+	orders    map[uuid.UUID]*orderState
+	unordered bool // events are sent as they are published, without waiting for the previous order status
+
+	statsMutex sync.Mutex                // Mutex to protect access to stats
+	stats      map[uuid.UUID]OrderStream // Copy of orders state for the admin endpoints
 }
 
-func NewWebhookHandler(s *service.Service) *WebhookHandler {
-	wh := &WebhookHandler{
-		service: s,
+// endsAt returns the time after which the order can no longer change according
+// to the workflow, e.g. after chinazes the refund window is kept open.
+func (o *orderState) endsAt() (time.Time, bool) {
+	if o.lastSentMessage == nil {
+		return time.Time{}, false
+	}
+
+	return workflow.For(o.lastSentMessage.OrderType).EndsAt(workflow.Step{
+		Status: o.lastSentMessage.OrderStatus,
+		At:     o.lastSentMessage.UpdatedAt,
+	})
+}
 
-		Notifier:       make(chan []byte),
-		newClients:     make(chan map[uuid.UUID]chan []byte),
-		closingClients: make(chan map[uuid.UUID]chan []byte),
-		clients:        make(map[uuid.UUID]map[*clientState]bool),
+// endTimeout returns a channel that fires when the order can no longer change,
+// or nil if it can.
+func (o *orderState) endTimeout() <-chan time.Time {
+	endsAt, ok := o.endsAt()
+	if !ok {
+		return nil
 	}
 
-	go wh.listen()
+	return time.After(time.Until(endsAt))
+}
 
-	return wh
+func (c *clientState) order(orderID uuid.UUID) *orderState {
+	state, ok := c.orders[orderID]
+	if !ok {
+		state = &orderState{}
+		c.orders[orderID] = state
+	}
+
+	return state
 }
 
-func (h *WebhookHandler) listen() {
-	for {
-		select {
-		case client := <-h.newClients:
-			h.clientsMutex.Lock()
-			for orderID, ch := range client {
-				if _, exists := h.clients[orderID]; !exists {
-					h.clients[orderID] = make(map[*clientState]bool)
-				}
-				h.clients[orderID][&clientState{messageChan: ch}] = true
-				log.Printf("Client added for order %s. %d registered clients", orderID, len(h.clients[orderID]))
-			}
-			h.clientsMutex.Unlock()
+type WebhookHandler struct {
+	service   *service.Service
+	broker    broker.Broker
+	cfg       config.SSEConfig
+	admission *admission
 
-		case client := <-h.closingClients:
-			h.clientsMutex.Lock()
-			for orderID, ch := range client {
-				delete(h.clients[orderID], &clientState{messageChan: ch})
-				log.Printf("Removed client for order %s. %d registered clients", orderID, len(h.clients[orderID]))
-			}
-			h.clientsMutex.Unlock()
+	clients      map[uuid.UUID]*clientState // Connected clients by subscription ID
+	clientsMutex sync.Mutex                 // Mutex to protect access to clients map
+}
 
-		case event := <-h.Notifier:
-			var eventMsg models.EventBody
-			err := json.Unmarshal(event, &eventMsg)
-			if err != nil {
-				log.Printf("Error unmarshalling event: %v", err)
-				continue
-			}
-			orderID, err := uuid.Parse(eventMsg.OrderID)
-			if err != nil {
-				continue
-			}
+func NewWebhookHandler(s *service.Service, b broker.Broker, cfg config.SSEConfig) *WebhookHandler {
+	return &WebhookHandler{
+		service:   s,
+		broker:    b,
+		cfg:       cfg,
+		admission: newAdmission(cfg.Admission),
 
-			h.clientsMutex.Lock()
-			if clients, exists := h.clients[orderID]; exists {
-				for client := range clients {
-					select {
-					case client.messageChan <- event:
-					default:
-						// Avoid blocking if the client is slow
-					}
-				}
-			}
-			h.clientsMutex.Unlock()
-		}
+		clients: make(map[uuid.UUID]*clientState),
+	}
+}
+
+// subscribe subscribes a new client to the topics. The subscription is closed when ctx is done.
+func (h *WebhookHandler) subscribe(
+	ctx context.Context, transport, remoteAddr string, topics ...broker.Topic,
+) (*clientState, error) {
+	sub, err := h.broker.Subscribe(ctx, topics...)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &clientState{
+		sub:         sub,
+		transport:   transport,
+		remoteAddr:  remoteAddr,
+		connectedAt: time.Now(),
+
+		orders: make(map[uuid.UUID]*orderState),
 	}
+
+	h.clientsMutex.Lock()
+	h.clients[sub.ID()] = client
+	h.clientsMutex.Unlock()
+
+	return client, nil
+}
+
+func (h *WebhookHandler) unsubscribe(client *clientState) {
+	client.sub.Close()
+
+	h.clientsMutex.Lock()
+	delete(h.clients, client.sub.ID())
+	h.clientsMutex.Unlock()
 }
 
 func (h *WebhookHandler) Stream(w http.ResponseWriter, r *http.Request) {
@@ -108,62 +155,406 @@ func (h *WebhookHandler) Stream(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	client := &clientState{
-		messageChan:     make(chan []byte, 5),
-		lastSentMessage: nil,
+	lastEventID, err := parseLastEventID(r)
+	if err != nil {
+		SendBadRequest(w, r, err)
+		return
 	}
 
-	h.newClients <- map[uuid.UUID]chan []byte{orderID: client.messageChan}
+	history, err := parseHistoryParams(r)
+	if err != nil {
+		SendBadRequest(w, r, err)
+		return
+	}
 
-	defer func() {
-		h.closingClients <- map[uuid.UUID]chan []byte{orderID: client.messageChan}
-	}()
+	release, ok := h.admit(w, r, orderID)
+	if !ok {
+		return
+	}
+	defer release()
 
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	client, err := h.subscribe(r.Context(), transportSSE, r.RemoteAddr, broker.OrderTopic(orderID))
+	if err != nil {
+		SendHTTPError(w, r, err)
+		return
+	}
+	defer h.unsubscribe(client)
+
+	historyEvents, err := h.orderHistory(r.Context(), client, orderID, lastEventID, history)
+	if err != nil {
+		SendHTTPError(w, r, err)
+		return
+	}
+
+	// The client has already received the end of the order, 204 stops EventSource from reconnecting.
+	if endsAt, ok := client.order(orderID).endsAt(); ok && len(historyEvents) == 0 && time.Now().After(endsAt) {
+		sendEmptyResponse(w, r, http.StatusNoContent)
+		return
+	}
+
+	h.serveStream(w, r, flusher, client, orderID, historyEvents)
+}
+
+// orderHistory returns the order events to replay to the client. If the client
+// resumes the stream, only events the workflow places after lastEventID are
+// returned, otherwise the history is limited by the history params.
+func (h *WebhookHandler) orderHistory(
+	ctx context.Context, client *clientState, orderID, lastEventID uuid.UUID, history historyParams,
+) ([]models.EventMsg, error) {
+	state := client.order(orderID)
+
+	if lastEventID != uuid.Nil {
+		lastSentMessage, historyEvents, err := h.service.GetEventHistoryAfter(ctx, orderID, lastEventID)
+		if err != nil {
+			return nil, err
+		}
+		state.lastSentMessage = lastSentMessage
+
+		return historyEvents, nil
+	}
+
+	if history.all() {
+		return h.service.GetEventHistory(ctx, orderID)
+	}
+
+	var (
+		historyEvents []models.EventMsg
+		err           error
+	)
+	if !history.none {
+		historyEvents, err = h.service.GetRecentEventHistory(ctx, orderID, history.since, history.limit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The earlier history is not replayed, so the first replayed event is sent without its previous status.
+	// If nothing is replayed, live events follow the current order status.
+	if len(historyEvents) != 0 {
+		state.historySkipped = true
+		return historyEvents, nil
+	}
+
+	state.lastSentMessage, err = h.service.GetLastEvent(ctx, orderID)
+
+	return nil, err
+}
+
+// StreamUser streams events of all orders of the user. The most recently
+// updated orders are replayed first, then live updates of any user order are pushed.
+func (h *WebhookHandler) StreamUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["user_id"])
+	if err != nil {
+		SendBadRequest(w, r, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported!", http.StatusInternalServerError)
+		return
+	}
+
+	release, ok := h.admit(w, r, uuid.Nil)
+	if !ok {
+		return
+	}
+	defer release()
+
+	client, err := h.subscribe(r.Context(), transportSSE, r.RemoteAddr, broker.UserTopic(userID))
+	if err != nil {
+		SendHTTPError(w, r, err)
+		return
+	}
+	defer h.unsubscribe(client)
 
-	historyEvents, err := h.service.GetEventHistory(r.Context(), orderID)
+	historyEvents, err := h.service.GetUserEventHistory(r.Context(), userID, h.cfg.UserHistoryOrders)
 	if err != nil {
 		SendHTTPError(w, r, err)
 		return
 	}
 
-	err = h.sendMsg(w, flusher, historyEvents, client)
+	h.serveStream(w, r, flusher, client, uuid.Nil, historyEvents)
+}
+
+// StreamAll streams live events of all orders. Events can be filtered
+// by status, is_final and user_id the same way as the orders list.
+func (h *WebhookHandler) StreamAll(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseStreamFilters(r)
+	if err != nil {
+		SendBadRequest(w, r, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported!", http.StatusInternalServerError)
+		return
+	}
+
+	release, ok := h.admit(w, r, uuid.Nil)
+	if !ok {
+		return
+	}
+	defer release()
+
+	client, err := h.subscribe(r.Context(), transportSSE, r.RemoteAddr, broker.FilterTopic(filter))
 	if err != nil {
 		SendHTTPError(w, r, err)
 		return
 	}
+	defer h.unsubscribe(client)
+	client.unordered = true
+
+	h.serveStream(w, r, flusher, client, uuid.Nil, nil)
+}
+
+// WatchPeer identifies a watcher for the connection limits the same way as a stream request.
+type WatchPeer struct {
+	RemoteAddr   string
+	APIKey       string
+	ForwardedFor []string
+}
+
+// Watch replays the order history and then passes live order events to send
+// until the context is done or the order can no longer change. Events are passed in the same order as to stream
+// clients. It returns models.ErrClientDisconnected if the watcher can't keep up or is disconnected by an admin,
+// and the admission errors if the watcher is over the connection limits.
+func (h *WebhookHandler) Watch(
+	ctx context.Context, orderID, lastEventID uuid.UUID, peer WatchPeer,
+	send func(eventMsg *models.EventMsg) error,
+) error {
+	id, err := h.admission.identify(peer.APIKey, peer.RemoteAddr, peer.ForwardedFor)
+	if err != nil {
+		return err
+	}
+
+	release, err := h.admission.acquire(orderID, id)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	client, err := h.subscribe(ctx, transportGRPC, peer.RemoteAddr, broker.OrderTopic(orderID))
+	if err != nil {
+		return err
+	}
+	defer h.unsubscribe(client)
+
+	historyEvents, err := h.orderHistory(ctx, client, orderID, lastEventID, historyParams{})
+	if err != nil {
+		return err
+	}
+
+	out := eventWriterFunc(send)
+	if err = h.sendMsg(out, historyEvents, client); err != nil {
+		return err
+	}
+
+	endTimeout := h.endTimeout(client, orderID)
+	gapTimeout := h.gapTimeout(client)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-client.sub.Done():
+			if ctx.Err() != nil {
+				return nil
+			}
+			return client.sub.Err()
+
+		case <-client.sub.Ready():
+			if err = h.sendQueuedMsg(out, client); err != nil {
+				return err
+			}
+
+			endTimeout = h.endTimeout(client, orderID)
+			gapTimeout = h.gapTimeout(client)
+
+		case <-gapTimeout:
+			if err = h.flushGaps(out, client); err != nil {
+				return err
+			}
+
+			endTimeout = h.endTimeout(client, orderID)
+			gapTimeout = h.gapTimeout(client)
+
+		case <-endTimeout:
+			return nil
+		}
+	}
+}
+
+// serveStream sends the history to the subscribed client and then pushes
+// live events until the client goes away. If orderID is set, the stream ends
+// when the order can no longer change.
+func (h *WebhookHandler) serveStream(
+	w http.ResponseWriter, r *http.Request, flusher http.Flusher,
+	client *clientState, orderID uuid.UUID,
+	historyEvents []models.EventMsg,
+) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if err := writeRetry(w, flusher, h.cfg.Retry.Std()); err != nil {
+		return
+	}
+
+	out := sseWriter{w: w, flusher: flusher}
+	if err := h.sendMsg(out, historyEvents, client); err != nil {
+		log.Printf("Error sending history: %v", err)
+		return
+	}
 
-	inactivityTimeout := time.NewTimer(models.InactivityTimeout)
-	defer inactivityTimeout.Stop()
+	var heartbeat <-chan time.Time
+	if h.cfg.HeartbeatInterval > 0 {
+		heartbeatTicker := time.NewTicker(h.cfg.HeartbeatInterval.Std())
+		defer heartbeatTicker.Stop()
+		heartbeat = heartbeatTicker.C
+	}
+
+	endTimeout := h.endTimeout(client, orderID)
+	idleTimeout := h.idleTimeout(client, orderID)
+	gapTimeout := h.gapTimeout(client)
 
 	for {
 		select {
 		case <-r.Context().Done():
-			h.closingClients <- map[uuid.UUID]chan []byte{orderID: client.messageChan}
 			return
 
-		case msg := <-client.messageChan:
-			inactivityTimeout.Reset(models.InactivityTimeout)
+		case <-client.sub.Done():
+			return
+
+		case <-client.sub.Ready():
+			if err := h.sendQueuedMsg(out, client); err != nil {
+				log.Printf("Error sending event: %v", err)
+				return
+			}
+
+			endTimeout = h.endTimeout(client, orderID)
+			idleTimeout = h.idleTimeout(client, orderID)
+			gapTimeout = h.gapTimeout(client)
 
-			var eventMsg models.EventMsg
-			if err := json.Unmarshal(msg, &eventMsg); err != nil {
-				SendHTTPError(w, r, err)
+		case <-gapTimeout:
+			if err := h.flushGaps(out, client); err != nil {
+				log.Printf("Error sending event: %v", err)
 				return
 			}
 
-			if err = h.sendMsg(w, flusher, []models.EventMsg{eventMsg}, client); err != nil {
-				SendHTTPError(w, r, err)
+			endTimeout = h.endTimeout(client, orderID)
+			idleTimeout = h.idleTimeout(client, orderID)
+			gapTimeout = h.gapTimeout(client)
+
+		case <-heartbeat:
+			if err := writeComment(w, flusher, "ping"); err != nil {
 				return
 			}
 
-		case <-inactivityTimeout.C:
-			h.closingClients <- map[uuid.UUID]chan []byte{orderID: client.messageChan}
+		case <-idleTimeout:
 			return
+
+		case <-endTimeout:
+			if err := writeEnd(w, flusher, client.order(orderID).lastSentMessage); err != nil {
+				log.Printf("Error sending end of stream: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// endTimeout returns a channel that fires when the streamed order can no
+// longer change. User streams and streams of all events never end.
+func (h *WebhookHandler) endTimeout(client *clientState, orderID uuid.UUID) <-chan time.Time {
+	if orderID == uuid.Nil {
+		return nil
+	}
+
+	return client.order(orderID).endTimeout()
+}
+
+// idleTimeout returns a channel that fires when the stream of an order in a final
+// status, whose end can't be computed because the workflow allows a transition
+// from the status at any time, has had no events for the configured max idle time.
+// The client may reconnect, so no end of stream is sent.
+func (h *WebhookHandler) idleTimeout(client *clientState, orderID uuid.UUID) <-chan time.Time {
+	if orderID == uuid.Nil || h.cfg.MaxIdle <= 0 {
+		return nil
+	}
+
+	state := client.order(orderID)
+	if state.lastSentMessage == nil || !workflow.For(state.lastSentMessage.OrderType).IsFinal(state.lastSentMessage.OrderStatus) {
+		return nil
+	}
+	if _, ok := state.endsAt(); ok {
+		return nil
+	}
+
+	return time.After(h.cfg.MaxIdle.Std())
+}
+
+// historyParams limit the order history replayed to a new stream client.
+type historyParams struct {
+	none  bool      // Only live events are sent
+	limit int       // The number of latest events, 0 means all
+	since time.Time // Only events updated at or after since, zero means all
+}
+
+func (p historyParams) all() bool {
+	return !p.none && p.limit == 0 && p.since.IsZero()
+}
+
+// parseHistoryParams reads the history query parameter, one of none, latest,
+// all or the number of latest events, and the since parameter in RFC 3339.
+func parseHistoryParams(r *http.Request) (historyParams, error) {
+	var (
+		params historyParams
+		err    error
+	)
+	historyStr := r.URL.Query().Get("history")
+	sinceStr := r.URL.Query().Get("since")
+
+	switch historyStr {
+	case "", historyAll:
+	case historyNone:
+		params.none = true
+	case historyLatest:
+		params.limit = 1
+	default:
+		params.limit, err = strconv.Atoi(historyStr)
+		if err != nil {
+			return historyParams{}, err
+		}
+		if params.limit <= 0 {
+			return historyParams{}, models.ErrBadRequest
+		}
+	}
+
+	if len(sinceStr) != 0 {
+		params.since, err = time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			return historyParams{}, err
 		}
 	}
+
+	return params, nil
+}
+
+// parseLastEventID reads the id of the last event the client has received.
+// Browsers send it in the Last-Event-ID header on reconnect, other clients
+// may pass it in the last_event_id query parameter.
+func parseLastEventID(r *http.Request) (uuid.UUID, error) {
+	lastEventIDStr := r.Header.Get("Last-Event-ID")
+	if len(lastEventIDStr) == 0 {
+		lastEventIDStr = r.URL.Query().Get("last_event_id")
+	}
+
+	if len(lastEventIDStr) == 0 {
+		return uuid.Nil, nil
+	}
+
+	return uuid.Parse(lastEventIDStr)
 }
 
 func (h *WebhookHandler) BroadcastMessage(w http.ResponseWriter, r *http.Request) {
@@ -174,28 +565,23 @@ func (h *WebhookHandler) BroadcastMessage(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	event, err := h.validateEventReq(req)
+	event, err := h.ValidateEventReq(req)
 	if err != nil {
 		SendInternalServerError(w, r, err)
 		return
 	}
 
+	// Stored events are published to the broker of every instance through the database.
 	if err = h.service.AddEvent(r.Context(), event, req.OrderStatus); err != nil {
 		SendHTTPError(w, r, err)
 		return
 	}
 
-	j, err := json.Marshal(req)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	h.Notifier <- []byte(j)
-
 	SendOK(w, r)
 }
 
-func (h *WebhookHandler) validateEventReq(req models.EventBody) (models.Event, error) {
+// ValidateEventReq parses the webhook event. The order status is resolved by the service.
+func (h *WebhookHandler) ValidateEventReq(req models.EventBody) (models.Event, error) {
 	eventID, err := uuid.Parse(req.EventID)
 	if err != nil {
 		return models.Event{}, err
@@ -231,104 +617,245 @@ func (h *WebhookHandler) validateEventReq(req models.EventBody) (models.Event, e
 		EventID:       eventID,
 		OrderID:       orderID,
 		UserID:        userID,
+		OrderType:     req.OrderType,
 		OrderStatusID: 0,
 		UpdatedAt:     updatedAt,
 		CreatedAt:     createdAt,
 	}, nil
 }
 
+// sendQueuedMsg sends the events published to the client since the last call.
+func (h *WebhookHandler) sendQueuedMsg(out eventWriter, client *clientState) error {
+	for _, msg := range client.sub.Receive() {
+		var err error
+		if msg.Coalesced && !client.unordered {
+			err = h.sendLatestMsg(out, msg.Event, client)
+		} else {
+			err = h.sendMsg(out, []models.EventMsg{msg.Event}, client)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (h *WebhookHandler) sendMsg(
-	w http.ResponseWriter, flusher http.Flusher,
+	out eventWriter,
 	events []models.EventMsg,
 	client *clientState,
 ) error {
 
+	defer client.updateStats()
+
 	for _, eventMsg := range events {
-		if allowToSendMsgToStream(client.lastSentMessage, &eventMsg) {
-			formattedMsg := fmt.Sprintf("%s\n\n", eventMsg)
-			_, err := w.Write([]byte(formattedMsg))
-			if err != nil {
+		if client.unordered {
+			if err := out.writeEvent(&eventMsg); err != nil {
 				return err
 			}
-			flusher.Flush()
+			continue
+		}
 
-			client.lastSentMessage = &eventMsg
+		state := client.order(eventMsg.OrderID)
+		if state.historySkipped || allowToSendMsgToStream(state.lastSentMessage, &eventMsg) {
+			if err := out.writeEvent(&eventMsg); err != nil {
+				return err
+			}
+
+			state.lastSentMessage = &eventMsg
+			state.historySkipped = false
 
-			if err = client.checkUnsentMsgToSend(w, flusher); err != nil {
+			if err := state.checkUnsentMsgToSend(out); err != nil {
 				return err
 			}
 			continue
 		}
 
-		client.storeUnSentMsg(&eventMsg)
+		state.storeUnSentMsg(&eventMsg)
+
+		if err := h.limitUnsentMsg(out, client); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (c *clientState) storeUnSentMsg(msg *models.EventMsg) {
-	c.unsentMsg = append(c.unsentMsg, msg)
-	sort.Slice(c.unsentMsg, func(i, j int) bool {
-		return c.unsentMsg[i].UpdatedAt.After(c.unsentMsg[j].UpdatedAt)
+// sendLatestMsg sends an event that replaced older events of the order in
+// the client queue. The skipped statuses will never arrive, so the event is
+// sent without waiting for them and older buffered events are discarded.
+func (h *WebhookHandler) sendLatestMsg(
+	out eventWriter,
+	eventMsg models.EventMsg,
+	client *clientState,
+) error {
+	defer client.updateStats()
+
+	state := client.order(eventMsg.OrderID)
+	if err := out.writeEvent(&eventMsg); err != nil {
+		return err
+	}
+
+	state.lastSentMessage = &eventMsg
+	state.historySkipped = false
+	state.unsentMsg = slices.DeleteFunc(state.unsentMsg, func(msg *models.EventMsg) bool {
+		return !msg.UpdatedAt.After(eventMsg.UpdatedAt)
 	})
+
+	return state.checkUnsentMsgToSend(out)
 }
 
-func (c *clientState) checkUnsentMsgToSend(w http.ResponseWriter, flusher http.Flusher) error {
-	l := len(c.unsentMsg)
-	for ; l > 0; l-- {
-		if allowToSendMsgToStream(c.lastSentMessage, c.unsentMsg[l-1]) {
-			msg, err := json.Marshal(c.unsentMsg[l-1])
-			if err != nil {
-				return err
-			}
+func (o *orderState) storeUnSentMsg(msg *models.EventMsg) {
+	if len(o.unsentMsg) == 0 {
+		o.gapSince = time.Now()
+	}
 
-			formattedMsg := fmt.Sprintf("%s\n\n", msg)
-			_, err = w.Write([]byte(formattedMsg))
-			if err != nil {
+	o.unsentMsg = append(o.unsentMsg, msg)
+	sort.Slice(o.unsentMsg, func(i, j int) bool {
+		return o.unsentMsg[i].UpdatedAt.After(o.unsentMsg[j].UpdatedAt)
+	})
+}
+
+func (o *orderState) checkUnsentMsgToSend(out eventWriter) error {
+	l := len(o.unsentMsg)
+	for ; l > 0; l-- {
+		if allowToSendMsgToStream(o.lastSentMessage, o.unsentMsg[l-1]) {
+			if err := out.writeEvent(o.unsentMsg[l-1]); err != nil {
 				return err
 			}
-			flusher.Flush()
 
-			c.lastSentMessage = c.unsentMsg[l-1]
+			o.lastSentMessage = o.unsentMsg[l-1]
 
-			c.unsentMsg = c.unsentMsg[:l-1]
+			o.unsentMsg = o.unsentMsg[:l-1]
+			o.gapSince = time.Now()
 		} else {
 			break
 		}
 	}
 
+	if len(o.unsentMsg) == 0 {
+		o.gapSince = time.Time{}
+	}
+
 	return nil
 }
 
-func allowToSendMsgToStream(lastSentMsg, eventMsg *models.EventMsg) bool {
-	if eventMsg.OrderStatus == models.CoolOrderCreated && lastSentMsg == nil {
-		return true
-	}
+// flushUnsentMsg sends all held back events in order without waiting for the
+// missing statuses. A gap is sent before every event that doesn't follow the
+// previous one, events older than the last sent one are discarded.
+func (o *orderState) flushUnsentMsg(out eventWriter) error {
+	for l := len(o.unsentMsg); l > 0; l-- {
+		eventMsg := o.unsentMsg[l-1]
+		o.unsentMsg = o.unsentMsg[:l-1]
 
-	if lastSentMsg != nil {
-		if (eventMsg.OrderStatus == models.SBUVarificationPending && lastSentMsg.OrderStatus == models.CoolOrderCreated) ||
-			canceledOrder(eventMsg.OrderStatus) {
-			return true
+		if o.lastSentMessage != nil && !eventMsg.UpdatedAt.After(o.lastSentMessage.UpdatedAt) {
+			continue
+		}
+
+		if !allowToSendMsgToStream(o.lastSentMessage, eventMsg) {
+			gap := &models.Gap{
+				OrderID:     eventMsg.OrderID,
+				UserID:      eventMsg.UserID,
+				OrderStatus: eventMsg.OrderStatus,
+			}
+			if o.lastSentMessage != nil {
+				gap.LastOrderStatus = o.lastSentMessage.OrderStatus
+			}
+
+			if err := out.writeGap(gap); err != nil {
+				return err
+			}
 		}
 
-		if (eventMsg.OrderStatus == models.ConfirmedByMayor && lastSentMsg.OrderStatus == models.SBUVarificationPending) ||
-			canceledOrder(eventMsg.OrderStatus) {
-			return true
+		if err := out.writeEvent(eventMsg); err != nil {
+			return err
 		}
 
-		if lastSentMsg.OrderStatus == models.ConfirmedByMayor && (eventMsg.OrderStatus == models.Chinazes ||
-			canceledOrder(eventMsg.OrderStatus)) {
-			return true
+		o.lastSentMessage = eventMsg
+	}
+
+	o.gapSince = time.Time{}
+
+	return nil
+}
+
+// gapTimeout returns a channel that fires when the client has waited for a
+// missing order status for the configured gap timeout, or nil if it doesn't wait.
+func (h *WebhookHandler) gapTimeout(client *clientState) <-chan time.Time {
+	if h.cfg.Reorder.GapTimeout <= 0 {
+		return nil
+	}
+
+	var gapSince time.Time
+	for _, state := range client.orders {
+		if len(state.unsentMsg) != 0 && (gapSince.IsZero() || state.gapSince.Before(gapSince)) {
+			gapSince = state.gapSince
 		}
+	}
 
-		if lastSentMsg.OrderStatus == models.Chinazes && eventMsg.OrderStatus == models.GiveMyMoneyBack {
-			return true
+	if gapSince.IsZero() {
+		return nil
+	}
+
+	return time.After(time.Until(gapSince.Add(h.cfg.Reorder.GapTimeout.Std())))
+}
+
+// flushGaps sends the held back events of orders that have waited for a missing status for the gap timeout.
+func (h *WebhookHandler) flushGaps(out eventWriter, client *clientState) error {
+	defer client.updateStats()
+
+	expired := time.Now().Add(-h.cfg.Reorder.GapTimeout.Std())
+	for _, state := range client.orders {
+		if len(state.unsentMsg) != 0 && !state.gapSince.After(expired) {
+			if err := state.flushUnsentMsg(out); err != nil {
+				return err
+			}
 		}
 	}
 
-	return false
+	return nil
+}
+
+// limitUnsentMsg keeps the number of held back events of the client within the
+// configured bound by flushing the orders that have waited the longest.
+func (h *WebhookHandler) limitUnsentMsg(out eventWriter, client *clientState) error {
+	if h.cfg.Reorder.MaxBuffered <= 0 {
+		return nil
+	}
+
+	for {
+		var (
+			unsent int
+			oldest *orderState
+		)
+		for _, state := range client.orders {
+			if len(state.unsentMsg) == 0 {
+				continue
+			}
+
+			unsent += len(state.unsentMsg)
+			if oldest == nil || state.gapSince.Before(oldest.gapSince) {
+				oldest = state
+			}
+		}
+
+		if unsent <= h.cfg.Reorder.MaxBuffered {
+			return nil
+		}
+
+		if err := oldest.flushUnsentMsg(out); err != nil {
+			return err
+		}
+	}
 }
 
-func canceledOrder(orderStatus string) bool {
-	return orderStatus == models.Failed || orderStatus == models.ChangedMyMind
+// allowToSendMsgToStream reports whether the event follows the last sent one in the workflow of the order type.
+func allowToSendMsgToStream(lastSentMsg, eventMsg *models.EventMsg) bool {
+	var from *workflow.Step
+	if lastSentMsg != nil {
+		from = &workflow.Step{Status: lastSentMsg.OrderStatus, At: lastSentMsg.UpdatedAt}
+	}
+
+	return workflow.For(eventMsg.OrderType).CanTransition(from, eventMsg.OrderStatus, eventMsg.UpdatedAt)
 }
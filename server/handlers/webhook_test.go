@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"sse/broker"
+	"sse/config"
+	"sse/models"
+	"sse/service"
+	"sse/workflow"
+)
+
+// memoryWebhookRepo keeps the events in the order they are stored.
+type memoryWebhookRepo struct {
+	service.WebhookRepo
+	events []models.FullEventInfo
+}
+
+func (m *memoryWebhookRepo) add(orderID uuid.UUID, status string, updatedAt time.Time) models.FullEventInfo {
+	event := models.FullEventInfo{
+		EventID:         uuid.New(),
+		OrderID:         orderID,
+		OrderType:       models.DefaultOrderType,
+		OrderStatusName: status,
+		UpdatedAt:       updatedAt,
+		CreatedAt:       updatedAt,
+	}
+	m.events = append(m.events, event)
+
+	return event
+}
+
+func (m *memoryWebhookRepo) GetOrderEvents(_ context.Context, orderID uuid.UUID) ([]models.FullEventInfo, error) {
+	var events []models.FullEventInfo
+	for _, event := range m.events {
+		if event.OrderID == orderID {
+			events = append(events, event)
+		}
+	}
+	slices.SortStableFunc(events, func(a, b models.FullEventInfo) int {
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	})
+
+	return events, nil
+}
+
+func (m *memoryWebhookRepo) GetFullEventByID(_ context.Context, eventID uuid.UUID) (*models.FullEventInfo, error) {
+	for _, event := range m.events {
+		if event.EventID == eventID {
+			return &event, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (m *memoryWebhookRepo) GetEventByID(_ context.Context, eventID uuid.UUID) (*models.Event, error) {
+	for _, event := range m.events {
+		if event.EventID == eventID {
+			return &models.Event{EventID: event.EventID, OrderID: event.OrderID, OrderType: event.OrderType}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (m *memoryWebhookRepo) GetLastUpdatedEventByOrderID(ctx context.Context, orderID uuid.UUID) (*models.FullEventInfo, error) {
+	events, _ := m.GetOrderEvents(ctx, orderID)
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	return &events[len(events)-1], nil
+}
+
+// GetOrderStatusByName knows the statuses of the default workflow.
+func (m *memoryWebhookRepo) GetOrderStatusByName(_ context.Context, name string) (*models.OrderStatus, error) {
+	for i, status := range workflow.Default.Statuses {
+		if status.Name == name {
+			status.ID = i + 1
+			return &status, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: unknown order status %q", models.ErrBadRequest, name)
+}
+
+func (m *memoryWebhookRepo) AddEvent(_ context.Context, event models.Event, eventMsg models.EventMsg) error {
+	m.events = append(m.events, models.FullEventInfo{
+		EventID:         event.EventID,
+		OrderID:         event.OrderID,
+		UserID:          event.UserID,
+		OrderType:       event.OrderType,
+		OrderStatusName: eventMsg.OrderStatus,
+		UpdatedAt:       event.UpdatedAt,
+		CreatedAt:       event.CreatedAt,
+	})
+
+	return nil
+}
+
+func newTestWebhookHandler(repo service.WebhookRepo) *WebhookHandler {
+	return NewWebhookHandler(
+		service.New(repo, nil, nil),
+		broker.NewMemory(config.SlowConsumerConfig{Policy: config.SlowConsumerDisconnect, Buffer: 5}),
+		config.SSEConfig{},
+	)
+}
+
+func poll(t *testing.T, h *WebhookHandler, orderID, after uuid.UUID, wait time.Duration) models.EventsPage {
+	t.Helper()
+
+	target := "/orders/" + orderID.String() + "/events/poll?wait=" + wait.String()
+	if after != uuid.Nil {
+		target += "&after=" + after.String()
+	}
+
+	r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, target, nil), map[string]string{"order_id": orderID.String()})
+	w := httptest.NewRecorder()
+	h.Poll(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("poll: status %d: %s", w.Code, w.Body.String())
+	}
+
+	var page models.EventsPage
+	if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+
+	return page
+}
+
+func statuses(events []models.EventMsg) []string {
+	res := make([]string, 0, len(events))
+	for _, event := range events {
+		res = append(res, event.OrderStatus)
+	}
+
+	return res
+}
+
+// An event stored before the last received one, but held back for the client
+// until its previous status arrives, must be sent when the client resumes.
+func TestResumeOutOfOrder(t *testing.T) {
+	repo := &memoryWebhookRepo{}
+	h := newTestWebhookHandler(repo)
+
+	orderID := uuid.New()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	repo.add(orderID, models.ConfirmedByMayor, start.Add(2*time.Second))
+	created := repo.add(orderID, models.CoolOrderCreated, start)
+
+	page := poll(t, h, orderID, uuid.Nil, 0)
+	if got, want := statuses(page.Events), []string{models.CoolOrderCreated}; !slices.Equal(got, want) {
+		t.Fatalf("first poll: got %v, want %v", got, want)
+	}
+	if page.Cursor != created.EventID.String() {
+		t.Fatalf("first poll: cursor %s, want %s", page.Cursor, created.EventID)
+	}
+
+	repo.add(orderID, models.SBUVarificationPending, start.Add(time.Second))
+
+	page = poll(t, h, orderID, created.EventID, 0)
+	want := []string{models.SBUVarificationPending, models.ConfirmedByMayor}
+	if got := statuses(page.Events); !slices.Equal(got, want) {
+		t.Fatalf("resumed poll: got %v, want %v", got, want)
+	}
+}
+
+// A poll of an order that can no longer change returns at once and tells the client to stop polling.
+func TestPollEndedOrder(t *testing.T) {
+	repo := &memoryWebhookRepo{}
+	h := newTestWebhookHandler(repo)
+
+	orderID := uuid.New()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	repo.add(orderID, models.CoolOrderCreated, start)
+	failed := repo.add(orderID, models.Failed, start.Add(time.Second))
+
+	page := poll(t, h, orderID, uuid.Nil, time.Minute)
+	if got, want := statuses(page.Events), []string{models.CoolOrderCreated, models.Failed}; !slices.Equal(got, want) {
+		t.Fatalf("first poll: got %v, want %v", got, want)
+	}
+	if !page.Ended {
+		t.Fatal("first poll: the order is not ended")
+	}
+
+	polledAt := time.Now()
+	page = poll(t, h, orderID, failed.EventID, time.Minute)
+	if time.Since(polledAt) > 5*time.Second {
+		t.Fatal("poll of an ended order waits for new events")
+	}
+
+	if len(page.Events) != 0 || !page.Ended || page.Cursor != failed.EventID.String() {
+		t.Fatalf("poll after the end: got %+v", page)
+	}
+}
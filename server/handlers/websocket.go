@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+
+	"sse/broker"
+	"sse/models"
+)
+
+const (
+	wsActionSubscribe   = "subscribe"
+	wsActionUnsubscribe = "unsubscribe"
+
+	wsEventSubscribed   = "subscribed"
+	wsEventUnsubscribed = "unsubscribed"
+	wsEventError        = "error"
+
+	wsWriteWait      = 10 * time.Second
+	wsMaxMessageSize = 4096
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// wsFrame is a JSON frame sent to a WebSocket client. Order events have the
+// same id, event and data as the text/event-stream frames.
+type wsFrame struct {
+	ID    string      `json:"id,omitempty"`
+	Event string      `json:"event"`
+	Data  interface{} `json:"data,omitempty"`
+}
+
+// wsCommand is a JSON frame received from a WebSocket client.
+type wsCommand struct {
+	Action      string `json:"action"`
+	OrderID     string `json:"order_id"`
+	LastEventID string `json:"last_event_id"`
+
+	err error
+}
+
+// wsSubscriptions are the orders subscribed over a WebSocket with
+// the functions releasing their per order connection slots.
+type wsSubscriptions map[uuid.UUID]func()
+
+// wsWriter sends events as JSON WebSocket frames.
+type wsWriter struct {
+	conn *websocket.Conn
+}
+
+func (ws wsWriter) writeEvent(eventMsg *models.EventMsg) error {
+	return ws.writeFrame(wsFrame{
+		ID:    eventMsg.EventID.String(),
+		Event: eventMsg.OrderStatus,
+		Data:  eventMsg,
+	})
+}
+
+func (ws wsWriter) writeGap(gap *models.Gap) error {
+	return ws.writeFrame(wsFrame{Event: models.EventGap, Data: gap})
+}
+
+func (ws wsWriter) writeFrame(frame wsFrame) error {
+	if err := ws.conn.SetWriteDeadline(time.Now().Add(wsWriteWait)); err != nil {
+		return err
+	}
+
+	return ws.conn.WriteJSON(frame)
+}
+
+func (ws wsWriter) writeError(err error) error {
+	return ws.writeFrame(wsFrame{Event: wsEventError, Data: map[string]string{"message": err.Error()}})
+}
+
+// StreamWS streams order events over a WebSocket. It replays the order history
+// the same way as Stream, and the client can subscribe to and unsubscribe
+// from other orders by sending {"action":"subscribe","order_id":"..."} frames.
+func (h *WebhookHandler) StreamWS(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(mux.Vars(r)["order_id"])
+	if err != nil {
+		SendBadRequest(w, r, err)
+		return
+	}
+
+	lastEventID, err := parseLastEventID(r)
+	if err != nil {
+		SendBadRequest(w, r, err)
+		return
+	}
+
+	history, err := parseHistoryParams(r)
+	if err != nil {
+		SendBadRequest(w, r, err)
+		return
+	}
+
+	// Every subscribed order is admitted separately, so the socket is admitted without an order.
+	release, ok := h.admit(w, r, uuid.Nil)
+	if !ok {
+		return
+	}
+	defer release()
+
+	releaseOrder, err := h.admission.acquireOrder(orderID)
+	if err != nil {
+		h.sendAdmissionError(w, r, err)
+		return
+	}
+	defer releaseOrder() // The release functions may be called more than once
+
+	subscriptions := make(wsSubscriptions)
+	defer func() {
+		for _, releaseOrder := range subscriptions {
+			releaseOrder()
+		}
+	}()
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Error upgrading connection: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	client, err := h.subscribe(r.Context(), transportWebSocket, r.RemoteAddr)
+	if err != nil {
+		log.Printf("Error subscribing to order %s: %v", orderID, err)
+		return
+	}
+	defer h.unsubscribe(client)
+
+	out := wsWriter{conn: conn}
+
+	err = h.subscribeWS(r.Context(), out, subscriptions, client, orderID, lastEventID, history, releaseOrder)
+	if err != nil {
+		log.Printf("Error subscribing to order %s: %v", orderID, err)
+		return
+	}
+
+	var (
+		heartbeat <-chan time.Time
+		pongWait  time.Duration
+	)
+	if h.cfg.HeartbeatInterval > 0 {
+		heartbeatTicker := time.NewTicker(h.cfg.HeartbeatInterval.Std())
+		defer heartbeatTicker.Stop()
+		heartbeat = heartbeatTicker.C
+		pongWait = 2 * h.cfg.HeartbeatInterval.Std()
+	}
+
+	commands := make(chan wsCommand)
+	readDone := make(chan struct{})
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go readWS(conn, pongWait, commands, readDone, stop)
+
+	endingOrderID, endTimeout := nextOrderEnd(subscriptions, client)
+	gapTimeout := h.gapTimeout(client)
+
+	for {
+		select {
+		case <-readDone:
+			return
+
+		case <-client.sub.Done():
+			return
+
+		case <-client.sub.Ready():
+			if err = h.sendQueuedMsg(out, client); err != nil {
+				log.Printf("Error sending event: %v", err)
+				return
+			}
+
+			endingOrderID, endTimeout = nextOrderEnd(subscriptions, client)
+			gapTimeout = h.gapTimeout(client)
+
+		case <-gapTimeout:
+			if err = h.flushGaps(out, client); err != nil {
+				log.Printf("Error sending event: %v", err)
+				return
+			}
+
+			endingOrderID, endTimeout = nextOrderEnd(subscriptions, client)
+			gapTimeout = h.gapTimeout(client)
+
+		case cmd := <-commands:
+			if err = h.handleWSCommand(r.Context(), out, subscriptions, client, cmd); err != nil {
+				log.Printf("Error handling command: %v", err)
+				return
+			}
+
+			endingOrderID, endTimeout = nextOrderEnd(subscriptions, client)
+			gapTimeout = h.gapTimeout(client)
+
+		case <-endTimeout:
+			err = out.writeFrame(wsFrame{
+				Event: models.EventEnd,
+				Data:  endOfStream(client.order(endingOrderID).lastSentMessage),
+			})
+			if err != nil {
+				log.Printf("Error sending end of stream: %v", err)
+				return
+			}
+
+			unsubscribeWS(subscriptions, client, endingOrderID)
+
+			if len(subscriptions) == 0 {
+				_ = conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, models.EventEnd),
+					time.Now().Add(wsWriteWait))
+				return
+			}
+
+			endingOrderID, endTimeout = nextOrderEnd(subscriptions, client)
+			gapTimeout = h.gapTimeout(client)
+
+		case <-heartbeat:
+			if err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// nextOrderEnd returns the subscribed order that can no longer change first
+// and a channel that fires at that moment. The channel is nil if every order can still change.
+func nextOrderEnd(subscriptions wsSubscriptions, client *clientState) (uuid.UUID, <-chan time.Time) {
+	var (
+		orderID uuid.UUID
+		endsAt  time.Time
+	)
+	for subOrderID := range subscriptions {
+		subEndsAt, ok := client.order(subOrderID).endsAt()
+		if ok && (orderID == uuid.Nil || subEndsAt.Before(endsAt)) {
+			orderID, endsAt = subOrderID, subEndsAt
+		}
+	}
+
+	if orderID == uuid.Nil {
+		return uuid.Nil, nil
+	}
+
+	return orderID, time.After(time.Until(endsAt))
+}
+
+// readWS reads client commands until the connection is closed. If pongWait is
+// set, the connection is considered dead when no pong arrives in time.
+func readWS(conn *websocket.Conn, pongWait time.Duration, commands chan<- wsCommand, readDone, stop chan struct{}) {
+	defer close(readDone)
+
+	conn.SetReadLimit(wsMaxMessageSize)
+	if pongWait > 0 {
+		_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(pongWait))
+		})
+	}
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var cmd wsCommand
+		if err = json.Unmarshal(msg, &cmd); err != nil {
+			cmd.err = err
+		}
+
+		select {
+		case commands <- cmd:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (h *WebhookHandler) handleWSCommand(
+	ctx context.Context, out wsWriter,
+	subscriptions wsSubscriptions, client *clientState,
+	cmd wsCommand,
+) error {
+	if cmd.err != nil {
+		return out.writeError(cmd.err)
+	}
+
+	orderID, err := uuid.Parse(cmd.OrderID)
+	if err != nil {
+		return out.writeError(err)
+	}
+
+	switch cmd.Action {
+	case wsActionSubscribe:
+		var lastEventID uuid.UUID
+		if len(cmd.LastEventID) != 0 {
+			if lastEventID, err = uuid.Parse(cmd.LastEventID); err != nil {
+				return out.writeError(err)
+			}
+		}
+
+		if _, ok := subscriptions[orderID]; ok {
+			return out.writeFrame(wsFrame{Event: wsEventSubscribed, Data: map[string]string{"order_id": orderID.String()}})
+		}
+
+		if maxOrders := h.cfg.Admission.MaxOrdersPerSocket; maxOrders > 0 && len(subscriptions) >= maxOrders {
+			return out.writeError(models.ErrTooManySubscriptions)
+		}
+
+		releaseOrder, err := h.admission.acquireOrder(orderID)
+		if err != nil {
+			return out.writeError(err)
+		}
+
+		return h.subscribeWS(ctx, out, subscriptions, client, orderID, lastEventID, historyParams{}, releaseOrder)
+
+	case wsActionUnsubscribe:
+		if _, ok := subscriptions[orderID]; ok {
+			unsubscribeWS(subscriptions, client, orderID)
+		}
+
+		return out.writeFrame(wsFrame{Event: wsEventUnsubscribed, Data: map[string]string{"order_id": orderID.String()}})
+
+	default:
+		return out.writeError(models.ErrBadRequest)
+	}
+}
+
+// subscribeWS registers the client for the order events and replays the order history.
+// releaseOrder is the admitted per order slot, it is released when the order is unsubscribed.
+// If the history can't be loaded, the order is unsubscribed and an error frame is sent.
+func (h *WebhookHandler) subscribeWS(
+	ctx context.Context, out wsWriter,
+	subscriptions wsSubscriptions, client *clientState,
+	orderID, lastEventID uuid.UUID, history historyParams, releaseOrder func(),
+) error {
+	client.sub.Add(broker.OrderTopic(orderID))
+	subscriptions[orderID] = releaseOrder
+
+	historyEvents, err := h.orderHistory(ctx, client, orderID, lastEventID, history)
+	if err != nil {
+		unsubscribeWS(subscriptions, client, orderID)
+		return out.writeError(err)
+	}
+
+	err = out.writeFrame(wsFrame{Event: wsEventSubscribed, Data: map[string]string{"order_id": orderID.String()}})
+	if err != nil {
+		return err
+	}
+
+	return h.sendMsg(out, historyEvents, client)
+}
+
+// unsubscribeWS stops the order events and forgets what was sent to the client for the order.
+func unsubscribeWS(subscriptions wsSubscriptions, client *clientState, orderID uuid.UUID) {
+	client.sub.Remove(broker.OrderTopic(orderID))
+	subscriptions[orderID]()
+	delete(subscriptions, orderID)
+	delete(client.orders, orderID)
+	client.updateStats()
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"sse/models"
+	"sse/service"
+)
+
+type WorkflowHandler struct {
+	service *service.Service
+}
+
+func NewWorkflowHandler(s *service.Service) *WorkflowHandler {
+	return &WorkflowHandler{service: s}
+}
+
+// GetWorkflow returns the workflow in use by this instance.
+func (h *WorkflowHandler) GetWorkflow(w http.ResponseWriter, r *http.Request) {
+	sendResponse(w, r, http.StatusOK, buildWorkflowBody(h.service.GetWorkflow()))
+}
+
+// ReloadWorkflow loads the workflow from the database on every instance, e.g. after a migration.
+func (h *WorkflowHandler) ReloadWorkflow(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.ReloadWorkflow(r.Context()); err != nil {
+		SendHTTPError(w, r, err)
+		return
+	}
+
+	sendResponse(w, r, http.StatusOK, buildWorkflowBody(h.service.GetWorkflow()))
+}
+
+func (h *WorkflowHandler) AddOrderStatus(w http.ResponseWriter, r *http.Request) {
+	var req models.OrderStatus
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendBadRequest(w, r, err)
+		return
+	}
+
+	if err := h.service.AddOrderStatus(r.Context(), models.OrderStatus{Name: req.Name, IsFinal: req.IsFinal}); err != nil {
+		SendHTTPError(w, r, err)
+		return
+	}
+
+	sendEmptyResponse(w, r, http.StatusCreated)
+}
+
+func (h *WorkflowHandler) AddStatusTransition(w http.ResponseWriter, r *http.Request) {
+	var req models.StatusTransitionBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendBadRequest(w, r, err)
+		return
+	}
+
+	transition := models.StatusTransition{OrderType: req.OrderType, From: req.From, To: req.To}
+	if len(req.Window) != 0 {
+		window, err := time.ParseDuration(req.Window)
+		if err != nil {
+			SendBadRequest(w, r, err)
+			return
+		}
+		transition.Window = window
+	}
+
+	if err := h.service.AddStatusTransition(r.Context(), transition); err != nil {
+		SendHTTPError(w, r, err)
+		return
+	}
+
+	sendEmptyResponse(w, r, http.StatusCreated)
+}
+
+func buildWorkflowBody(definition models.WorkflowDefinition) models.WorkflowBody {
+	res := models.WorkflowBody{
+		Statuses:    definition.Statuses,
+		Transitions: make([]models.StatusTransitionBody, 0, len(definition.Transitions)),
+	}
+
+	for _, t := range definition.Transitions {
+		transition := models.StatusTransitionBody{OrderType: t.OrderType, From: t.From, To: t.To}
+		if t.Window != 0 {
+			transition.Window = t.Window.String()
+		}
+		res.Transitions = append(res.Transitions, transition)
+	}
+
+	return res
+}
@@ -2,24 +2,35 @@ package http
 
 import (
 	"github.com/gorilla/mux"
+	"log"
 	"net/http"
 
+	"sse/config"
 	"sse/server/handlers"
 )
 
 type Controller struct {
-	router *mux.Router
+	router     *mux.Router
+	cfgAdmin   config.AdminConfig
+	cfgWebhook config.WebhookConfig
 
 	wh *handlers.WebhookHandler
 	o  *handlers.OrdersHandler
+	wf *handlers.WorkflowHandler
 }
 
-func NewController(wh *handlers.WebhookHandler, o *handlers.OrdersHandler) *Controller {
+func NewController(
+	wh *handlers.WebhookHandler, o *handlers.OrdersHandler, wf *handlers.WorkflowHandler,
+	cfgAdmin config.AdminConfig, cfgWebhook config.WebhookConfig,
+) *Controller {
 	r := &Controller{
-		router: mux.NewRouter(),
+		router:     mux.NewRouter(),
+		cfgAdmin:   cfgAdmin,
+		cfgWebhook: cfgWebhook,
 
 		wh: wh,
 		o:  o,
+		wf: wf,
 	}
 
 	r.initRoutes()
@@ -30,8 +41,34 @@ func NewController(wh *handlers.WebhookHandler, o *handlers.OrdersHandler) *Cont
 func (c *Controller) initRoutes() {
 	c.router.Use(mux.CORSMethodMiddleware(c.router))
 
-	c.router.HandleFunc("/webhooks/payments/orders", c.wh.BroadcastMessage).Methods(http.MethodPost)
+	webhooks := c.router.PathPrefix("/webhooks/payments").Subrouter()
+	webhooks.Use(handlers.WebhookSignature(c.cfgWebhook))
+	webhooks.HandleFunc("/orders", c.wh.BroadcastMessage).Methods(http.MethodPost)
+	webhooks.HandleFunc("/orders:batch", c.wh.BroadcastBatch).Methods(http.MethodPost)
+
 	c.router.HandleFunc("/orders/{order_id}/events", c.wh.Stream).Methods(http.MethodGet)
+	c.router.HandleFunc("/orders/{order_id}/events/poll", c.wh.Poll).Methods(http.MethodGet)
+	c.router.HandleFunc("/orders/{order_id}/ws", c.wh.StreamWS).Methods(http.MethodGet)
+	c.router.HandleFunc("/users/{user_id}/events", c.wh.StreamUser).Methods(http.MethodGet)
+	c.router.HandleFunc("/events", c.wh.StreamAll).Methods(http.MethodGet)
 
 	c.router.HandleFunc("/orders", c.o.GetOrdersByFilter).Methods(http.MethodGet)
+
+	// Admin endpoints change the workflow and disconnect clients, they are not served without a token.
+	if len(c.cfgAdmin.Token) == 0 {
+		log.Println("admin.token is not set, admin endpoints are disabled")
+		return
+	}
+
+	admin := c.router.PathPrefix("/admin").Subrouter()
+	admin.Use(handlers.AdminAuth(c.cfgAdmin.Token))
+	admin.HandleFunc("/connections", c.wh.GetConnections).Methods(http.MethodGet)
+	admin.HandleFunc("/subscriptions", c.wh.GetSubscriptions).Methods(http.MethodGet)
+	admin.HandleFunc("/subscriptions/{subscription_id}", c.wh.DisconnectSubscription).Methods(http.MethodDelete)
+	admin.HandleFunc("/orders/{order_id}/subscriptions", c.wh.GetOrderSubscriptions).Methods(http.MethodGet)
+	admin.HandleFunc("/orders/{order_id}/subscriptions", c.wh.DisconnectOrderSubscriptions).Methods(http.MethodDelete)
+	admin.HandleFunc("/workflow", c.wf.GetWorkflow).Methods(http.MethodGet)
+	admin.HandleFunc("/workflow/reload", c.wf.ReloadWorkflow).Methods(http.MethodPost)
+	admin.HandleFunc("/workflow/statuses", c.wf.AddOrderStatus).Methods(http.MethodPost)
+	admin.HandleFunc("/workflow/transitions", c.wf.AddStatusTransition).Methods(http.MethodPost)
 }
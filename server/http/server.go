@@ -7,16 +7,19 @@ import (
 	"time"
 
 	"sse/config"
+	"sse/server/grpc"
 )
 
 type Server struct {
 	server     *http.Server
 	controller *Controller
+	grpcServer *grpc.Server
 }
 
-func NewHTPPServer(controller *Controller, cfgHTTP config.HTTPServerConfig) *Server {
+func NewHTPPServer(controller *Controller, cfgHTTP config.HTTPServerConfig, grpcServer *grpc.Server) *Server {
 	return &Server{
 		controller: controller,
+		grpcServer: grpcServer,
 		server: &http.Server{
 			Addr:    ":" + cfgHTTP.Port,
 			Handler: controller.router,
@@ -33,15 +36,29 @@ func (s *Server) Run(ctx context.Context) {
 		}
 	}()
 
+	go func() {
+		if err := s.grpcServer.Serve(); err != nil {
+			log.Fatalf("grpc listen: %s\n", err)
+		}
+	}()
+
 	<-ctx.Done()
 
 	log.Println("shutting down gracefully, press Ctrl+C again to force")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
+
+	grpcStopped := make(chan struct{})
+	go func() {
+		s.grpcServer.Shutdown(ctx)
+		close(grpcStopped)
+	}()
+
 	if err := s.server.Shutdown(ctx); err != nil {
 		log.Fatal("Server forced to shutdown: ", err)
 	}
+	<-grpcStopped
 
 	log.Println("Server exiting")
 }
@@ -14,6 +14,16 @@ func (s *Service) GetOrders(ctx context.Context, filters *models.OrderFilter) ([
 	return buildResponse(res), nil
 }
 
+// ListOrders returns the orders matching the filter as stream events, with their timestamps as time.Time.
+func (s *Service) ListOrders(ctx context.Context, filters *models.OrderFilter) ([]models.EventMsg, error) {
+	res, err := s.OrderRepo.GetOrdersByFilter(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildEventMsgs(res), nil
+}
+
 func buildResponse(events []models.FullEventInfo) []models.EventBody {
 	if events == nil || len(events) == 0 {
 		return nil
@@ -33,6 +43,7 @@ func buildEventBody(event *models.FullEventInfo) *models.EventBody {
 		EventID:     event.EventID.String(),
 		OrderID:     event.OrderID.String(),
 		UserID:      event.UserID.String(),
+		OrderType:   event.OrderType,
 		OrderStatus: event.OrderStatusName,
 		UpdatedAt:   event.UpdatedAt.String(),
 		CreatedAt:   event.CreatedAt.String(),
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -11,18 +12,23 @@ import (
 type Service struct {
 	WebhookRepo
 	OrderRepo
+	WorkflowRepo
 }
 
-func New(webhookRepo WebhookRepo, orderRepo OrderRepo) *Service {
+func New(webhookRepo WebhookRepo, orderRepo OrderRepo, workflowRepo WorkflowRepo) *Service {
 	return &Service{
 		webhookRepo,
 		orderRepo,
+		workflowRepo,
 	}
 }
 
 type WebhookRepo interface {
-	AddEvent(ctx context.Context, event models.Event) error
+	AddEvent(ctx context.Context, event models.Event, eventMsg models.EventMsg) error
 	GetOrderEvents(ctx context.Context, orderID uuid.UUID) ([]models.FullEventInfo, error)
+	GetRecentOrderEvents(ctx context.Context, orderID uuid.UUID, since time.Time, limit int) ([]models.FullEventInfo, error)
+	GetFullEventByID(ctx context.Context, eventID uuid.UUID) (*models.FullEventInfo, error)
+	GetUserEvents(ctx context.Context, userID uuid.UUID, ordersLimit int) ([]models.FullEventInfo, error)
 	GetOrderStatusByName(ctx context.Context, name string) (*models.OrderStatus, error)
 	GetEventByID(ctx context.Context, eventID uuid.UUID) (*models.Event, error)
 	GetLastUpdatedEventByOrderID(ctx context.Context, orderID uuid.UUID) (*models.FullEventInfo, error)
@@ -31,3 +37,10 @@ type WebhookRepo interface {
 type OrderRepo interface {
 	GetOrdersByFilter(ctx context.Context, filters *models.OrderFilter) ([]models.FullEventInfo, error)
 }
+
+type WorkflowRepo interface {
+	GetWorkflowDefinition(ctx context.Context) (*models.WorkflowDefinition, error)
+	AddOrderStatus(ctx context.Context, status models.OrderStatus) error
+	AddStatusTransition(ctx context.Context, transition models.StatusTransition) error
+	NotifyWorkflowChanged(ctx context.Context) error
+}
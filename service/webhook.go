@@ -2,10 +2,13 @@ package service
 
 import (
 	"context"
+	"slices"
+	"time"
 
 	"github.com/google/uuid"
 
 	"sse/models"
+	"sse/workflow"
 )
 
 func (s *Service) AddEvent(ctx context.Context, event models.Event, statusName string) error {
@@ -28,7 +31,20 @@ func (s *Service) AddEvent(ctx context.Context, event models.Event, statusName s
 		return err
 	}
 
+	if len(event.OrderType) == 0 {
+		event.OrderType = models.DefaultOrderType
+		if lastEvent != nil {
+			event.OrderType = lastEvent.OrderType
+		}
+	}
+	if !workflow.Exists(event.OrderType) {
+		return models.ErrBadRequest
+	}
+
 	if lastEvent != nil {
+		if lastEvent.OrderType != event.OrderType {
+			return models.ErrBadRequest
+		}
 		if err = s.validateEvent(event, *lastEvent, eventOrderStatus); err != nil {
 			return err
 		}
@@ -36,7 +52,17 @@ func (s *Service) AddEvent(ctx context.Context, event models.Event, statusName s
 
 	event.OrderStatusID = eventOrderStatus.ID
 
-	if err = s.WebhookRepo.AddEvent(ctx, event); err != nil {
+	eventMsg := models.EventMsg{
+		EventID:     event.EventID,
+		OrderID:     event.OrderID,
+		UserID:      event.UserID,
+		OrderType:   event.OrderType,
+		OrderStatus: eventOrderStatus.Name,
+		UpdatedAt:   event.UpdatedAt,
+		CreatedAt:   event.CreatedAt,
+	}
+
+	if err = s.WebhookRepo.AddEvent(ctx, event, eventMsg); err != nil {
 		return err
 	}
 
@@ -49,34 +75,90 @@ func (s *Service) GetEventHistory(ctx context.Context, orderID uuid.UUID) ([]mod
 		return nil, err
 	}
 
-	res := make([]models.EventMsg, 0, len(eventHistory))
-	for i := range eventHistory {
-		res = append(res, models.EventMsg{
-			EventID:     eventHistory[i].EventID,
-			OrderID:     eventHistory[i].OrderID,
-			UserID:      eventHistory[i].UserID,
-			OrderStatus: eventHistory[i].OrderStatusName,
-			UpdatedAt:   eventHistory[i].UpdatedAt,
-			CreatedAt:   eventHistory[i].CreatedAt,
-		})
+	return buildEventMsgs(eventHistory), nil
+}
+
+// GetEventHistoryAfter returns the event the client has last seen and the order
+// events the workflow places after it. Events are delivered out of order, so an
+// event stored before the last seen one may still be unsent and is returned too.
+// If lastEventID is unknown or belongs to another order, the whole history is
+// returned and the last seen event is nil.
+func (s *Service) GetEventHistoryAfter(ctx context.Context, orderID, lastEventID uuid.UUID) (*models.EventMsg, []models.EventMsg, error) {
+	lastEvent, err := s.WebhookRepo.GetFullEventByID(ctx, lastEventID)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return res, err
+	history, err := s.GetEventHistory(ctx, orderID)
+	if err != nil || lastEvent == nil || lastEvent.OrderID != orderID {
+		return nil, history, err
+	}
+
+	w := workflow.For(lastEvent.OrderType)
+	history = slices.DeleteFunc(history, func(eventMsg models.EventMsg) bool {
+		return eventMsg.EventID == lastEvent.EventID || eventMsg.OrderStatus == lastEvent.OrderStatusName ||
+			w.Precedes(eventMsg.OrderStatus, lastEvent.OrderStatusName)
+	})
+
+	return buildEventMsg(lastEvent), history, nil
 }
 
-func (s *Service) validateEvent(event models.Event, lastEvent models.FullEventInfo, eventOrderStatus *models.OrderStatus) error {
-	if !eventOrderStatus.IsFinal || !lastEvent.IsFinal ||
-		(lastEvent.OrderStatusName == models.GiveMyMoneyBack && eventOrderStatus.ID == models.ChinazesID) {
-		return nil
+// GetRecentEventHistory returns the limit latest order events updated at or after since.
+// Zero since and limit mean no bound.
+func (s *Service) GetRecentEventHistory(
+	ctx context.Context, orderID uuid.UUID, since time.Time, limit int,
+) ([]models.EventMsg, error) {
+	eventHistory, err := s.WebhookRepo.GetRecentOrderEvents(ctx, orderID, since, limit)
+	if err != nil {
+		return nil, err
 	}
 
-	if lastEvent.OrderStatusID == models.ChinazesID && eventOrderStatus.ID == models.GiveMyMoneyBackID {
-		if event.UpdatedAt.Sub(lastEvent.UpdatedAt) > models.GiveMyMoneyBackTimeout {
-			return models.ErrAlreadyProcessed
-		} else {
-			return nil
-		}
+	return buildEventMsgs(eventHistory), nil
+}
+
+// GetLastEvent returns the latest order event or nil if the order has no events.
+func (s *Service) GetLastEvent(ctx context.Context, orderID uuid.UUID) (*models.EventMsg, error) {
+	lastEvent, err := s.WebhookRepo.GetLastUpdatedEventByOrderID(ctx, orderID)
+	if err != nil || lastEvent == nil {
+		return nil, err
+	}
+
+	return buildEventMsg(lastEvent), nil
+}
+
+// GetUserEventHistory returns events of the user's most recently updated orders.
+func (s *Service) GetUserEventHistory(ctx context.Context, userID uuid.UUID, ordersLimit int) ([]models.EventMsg, error) {
+	eventHistory, err := s.WebhookRepo.GetUserEvents(ctx, userID, ordersLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildEventMsgs(eventHistory), nil
+}
+
+func (s *Service) validateEvent(event models.Event, lastEvent models.FullEventInfo, eventOrderStatus *models.OrderStatus) error {
+	from := workflow.Step{Status: lastEvent.OrderStatusName, At: lastEvent.UpdatedAt}
+
+	return workflow.For(event.OrderType).Accept(from, eventOrderStatus.Name, event.UpdatedAt)
+}
+
+func buildEventMsgs(events []models.FullEventInfo) []models.EventMsg {
+	res := make([]models.EventMsg, 0, len(events))
+	for i := range events {
+		res = append(res, *buildEventMsg(&events[i]))
 	}
 
-	return models.ErrAlreadyExistsFinalStatus
+	return res
+}
+
+func buildEventMsg(event *models.FullEventInfo) *models.EventMsg {
+	return &models.EventMsg{
+		EventID:     event.EventID,
+		OrderID:     event.OrderID,
+		UserID:      event.UserID,
+		OrderType:   event.OrderType,
+		OrderStatus: event.OrderStatusName,
+		UpdatedAt:   event.UpdatedAt,
+		CreatedAt:   event.CreatedAt,
+	}
 }
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"log"
+	"slices"
+
+	"sse/models"
+	"sse/workflow"
+)
+
+// LoadWorkflow replaces the workflows in use with the ones stored in the database.
+func (s *Service) LoadWorkflow(ctx context.Context) error {
+	definition, err := s.WorkflowRepo.GetWorkflowDefinition(ctx)
+	if err != nil {
+		return err
+	}
+
+	workflow.Load(*definition)
+	log.Printf("Workflow loaded: %d statuses, %d transitions", len(definition.Statuses), len(definition.Transitions))
+
+	return nil
+}
+
+// ReloadWorkflow loads the workflow from the database and tells the other instances to reload it.
+func (s *Service) ReloadWorkflow(ctx context.Context) error {
+	if err := s.LoadWorkflow(ctx); err != nil {
+		return err
+	}
+
+	return s.WorkflowRepo.NotifyWorkflowChanged(ctx)
+}
+
+func (s *Service) GetWorkflow() models.WorkflowDefinition {
+	return workflow.Definition()
+}
+
+// AddOrderStatus stores a new status and reloads the workflow on every instance.
+func (s *Service) AddOrderStatus(ctx context.Context, status models.OrderStatus) error {
+	if len(status.Name) == 0 {
+		return models.ErrBadRequest
+	}
+
+	if err := s.WorkflowRepo.AddOrderStatus(ctx, status); err != nil {
+		return err
+	}
+
+	return s.ReloadWorkflow(ctx)
+}
+
+// AddStatusTransition stores a new transition between existing statuses and reloads the workflow on every instance.
+// The first transition of a new order type creates its workflow.
+func (s *Service) AddStatusTransition(ctx context.Context, transition models.StatusTransition) error {
+	if len(transition.OrderType) == 0 {
+		transition.OrderType = models.DefaultOrderType
+	}
+
+	definition, err := s.WorkflowRepo.GetWorkflowDefinition(ctx)
+	if err != nil {
+		return err
+	}
+
+	statusExists := func(name string) bool {
+		return slices.ContainsFunc(definition.Statuses, func(status models.OrderStatus) bool {
+			return status.Name == name
+		})
+	}
+	if (len(transition.From) != 0 && !statusExists(transition.From)) || !statusExists(transition.To) ||
+		transition.Window < 0 {
+		return models.ErrBadRequest
+	}
+
+	if err = s.WorkflowRepo.AddStatusTransition(ctx, transition); err != nil {
+		return err
+	}
+
+	return s.ReloadWorkflow(ctx)
+}
@@ -0,0 +1,88 @@
+// Package signature signs and verifies webhook bodies. The signature header is
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">" and may hold several
+// v1 values, so a provider can sign with the old and the new secret while they are rotated.
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const Header = "X-Signature"
+
+var (
+	ErrMissingSignature = errors.New("missing or malformed signature")
+	ErrStaleSignature   = errors.New("signature timestamp is outside the tolerance")
+	ErrInvalidSignature = errors.New("signature doesn't match any secret")
+)
+
+// Sign returns the header value signing the body at t with every secret.
+func Sign(body []byte, t time.Time, secrets ...string) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+
+	header := "t=" + timestamp
+	for _, secret := range secrets {
+		header += ",v1=" + hex.EncodeToString(mac(secret, timestamp, body))
+	}
+
+	return header
+}
+
+// Verify checks that the header signs the body with one of the secrets and its
+// timestamp is within tolerance of now. 0 tolerance doesn't check the timestamp.
+func Verify(header string, body []byte, secrets []string, tolerance time.Duration, now time.Time) error {
+	var timestamp string
+	var signatures [][]byte
+
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrMissingSignature
+		}
+
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature, err := hex.DecodeString(value)
+			if err != nil {
+				return ErrMissingSignature
+			}
+			signatures = append(signatures, signature)
+		}
+	}
+
+	t, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrMissingSignature
+	}
+
+	if age := now.Sub(time.Unix(t, 0)).Abs(); tolerance > 0 && age > tolerance {
+		return ErrStaleSignature
+	}
+
+	for _, secret := range secrets {
+		expected := mac(secret, timestamp, body)
+		for _, signature := range signatures {
+			if hmac.Equal(signature, expected) {
+				return nil
+			}
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+func mac(secret, timestamp string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+
+	return h.Sum(nil)
+}
@@ -0,0 +1,67 @@
+package signature
+
+import (
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"event_id":"5999cfb6-a335-409a-879c-68b3b59aa660","order_status":"chinazes"}`)
+	now := time.Unix(1700000000, 0)
+	tolerance := 5 * time.Minute
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	v1 := func(secret string) string {
+		return "v1=" + hex.EncodeToString(mac(secret, timestamp, body))
+	}
+
+	tests := []struct {
+		name    string
+		header  string
+		body    []byte
+		secrets []string
+		anyTime bool // Verify without a tolerance
+		want    error
+	}{
+		{name: "valid", header: Sign(body, now, "new"), secrets: []string{"new"}},
+		{name: "signed with old and new secrets", header: Sign(body, now, "old", "new"), secrets: []string{"new"}},
+		{name: "signed with old secret while rotating", header: Sign(body, now, "old"), secrets: []string{"new", "old"}},
+		{name: "v1 values in any order", header: "t=" + timestamp + "," + v1("other") + "," + v1("new"), secrets: []string{"new"}},
+		{name: "spaces after commas", header: "t=" + timestamp + ", " + v1("new"), secrets: []string{"new"}},
+		{name: "unknown keys", header: "t=" + timestamp + ",v0=abc," + v1("new"), secrets: []string{"new"}},
+		{name: "wrong secret", header: Sign(body, now, "old"), secrets: []string{"new"}, want: ErrInvalidSignature},
+		{name: "tampered body", header: Sign(body, now, "new"), body: []byte(`{"order_status":"give_my_money_back"}`), secrets: []string{"new"}, want: ErrInvalidSignature},
+		{name: "tampered timestamp", header: "t=" + strconv.FormatInt(now.Unix()+1, 10) + "," + v1("new"), secrets: []string{"new"}, want: ErrInvalidSignature},
+		{name: "at tolerance", header: Sign(body, now.Add(-tolerance), "new"), secrets: []string{"new"}},
+		{name: "stale", header: Sign(body, now.Add(-tolerance-time.Second), "new"), secrets: []string{"new"}, want: ErrStaleSignature},
+		{name: "future", header: Sign(body, now.Add(tolerance+time.Second), "new"), secrets: []string{"new"}, want: ErrStaleSignature},
+		{name: "stale without tolerance", header: Sign(body, now.Add(-24*time.Hour), "new"), secrets: []string{"new"}, anyTime: true},
+		{name: "empty header", header: "", secrets: []string{"new"}, want: ErrMissingSignature},
+		{name: "missing t", header: v1("new"), secrets: []string{"new"}, want: ErrMissingSignature},
+		{name: "malformed t", header: "t=yesterday," + v1("new"), secrets: []string{"new"}, want: ErrMissingSignature},
+		{name: "missing v1", header: "t=" + timestamp, secrets: []string{"new"}, want: ErrMissingSignature},
+		{name: "malformed hex", header: "t=" + timestamp + ",v1=not-hex", secrets: []string{"new"}, want: ErrMissingSignature},
+		{name: "part without value", header: "t=" + timestamp + ",v1", secrets: []string{"new"}, want: ErrMissingSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqBody := body
+			if tt.body != nil {
+				reqBody = tt.body
+			}
+			tol := tolerance
+			if tt.anyTime {
+				tol = 0
+			}
+
+			err := Verify(tt.header, reqBody, tt.secrets, tol, now)
+			if !errors.Is(err, tt.want) || (err == nil) != (tt.want == nil) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
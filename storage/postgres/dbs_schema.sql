@@ -1,6 +1,6 @@
 CREATE TABLE IF NOT EXISTS "order_statuses" (
                                                 id serial NOT NULL PRIMARY KEY,
-                                                "name" varchar(50) NOT NULL,
+                                                "name" varchar(50) NOT NULL UNIQUE,
     "is_final" boolean DEFAULT false
     );
 
@@ -12,10 +12,44 @@ INSERT INTO "order_statuses" (name, is_final) VALUES ('failed', true);
 INSERT INTO "order_statuses" (name, is_final) VALUES ('chinazes', true);
 INSERT INTO "order_statuses" (name, is_final) VALUES ('give_my_money_back', true);
 
+CREATE TABLE IF NOT EXISTS "order_status_transitions" (
+                                                id serial NOT NULL PRIMARY KEY,
+                                                "order_type" varchar(50) NOT NULL DEFAULT 'payment',
+                                                "from_status_id" int,
+                                                "to_status_id" int NOT NULL,
+                                                "time_window" interval,
+
+                                                FOREIGN KEY ("from_status_id") REFERENCES "order_statuses" ("id"),
+                                                FOREIGN KEY ("to_status_id") REFERENCES "order_statuses" ("id")
+    );
+
+-- from_status_id is NULL for statuses orders start in, time_window is NULL if the transition is allowed any time
+CREATE UNIQUE INDEX "index_order_status_transitions_on_from_and_to"
+    ON "order_status_transitions" ("order_type", COALESCE("from_status_id", 0), "to_status_id");
+
+INSERT INTO "order_status_transitions" (from_status_id, to_status_id, time_window)
+SELECT fs.id, ts.id, t.time_window::interval
+FROM (VALUES
+          (NULL, 'cool_order_created', NULL),
+          ('cool_order_created', 'sbu_varification_pending', NULL),
+          ('cool_order_created', 'changed_my_mind', NULL),
+          ('cool_order_created', 'failed', NULL),
+          ('sbu_varification_pending', 'confirmed_by_mayor', NULL),
+          ('sbu_varification_pending', 'changed_my_mind', NULL),
+          ('sbu_varification_pending', 'failed', NULL),
+          ('confirmed_by_mayor', 'chinazes', NULL),
+          ('confirmed_by_mayor', 'changed_my_mind', NULL),
+          ('confirmed_by_mayor', 'failed', NULL),
+          ('chinazes', 'give_my_money_back', '30 seconds')
+     ) AS t (from_name, to_name, time_window)
+         LEFT JOIN "order_statuses" fs ON fs.name = t.from_name
+         JOIN "order_statuses" ts ON ts.name = t.to_name;
+
 CREATE TABLE IF NOT EXISTS "events" (
                                         "event_id" uuid NOT NULL PRIMARY KEY,
                                         "order_id" uuid NOT NULL,
                                         "user_id" uuid NOT NULL,
+                                        "order_type" varchar(50) NOT NULL DEFAULT 'payment',
                                         "order_status_id" int NOT NULL,
                                         "updated_at" timestamp NOT NULL,
                                         "created_at" timestamp NOT NULL,
@@ -24,3 +58,19 @@ CREATE TABLE IF NOT EXISTS "events" (
     );
 
 CREATE INDEX "index_events_on_order_status_id" ON "events" ("order_status_id");
+CREATE INDEX "index_events_on_order_id_and_updated_at" ON "events" ("order_id", "updated_at");
+CREATE INDEX "index_events_on_user_id" ON "events" ("user_id");
+CREATE INDEX "index_events_on_order_type" ON "events" ("order_type");
+
+CREATE TABLE IF NOT EXISTS "events_outbox" (
+                                        "id" bigserial NOT NULL PRIMARY KEY,
+                                        "event_id" uuid NOT NULL,
+                                        "payload" text NOT NULL,
+                                        "created_at" timestamp NOT NULL DEFAULT now(),
+                                        "dispatched_at" timestamptz,
+
+                                        FOREIGN KEY ("event_id") REFERENCES "events" ("event_id")
+    );
+
+CREATE INDEX "index_events_outbox_on_undispatched" ON "events_outbox" ("id") WHERE "dispatched_at" IS NULL;
+CREATE INDEX "index_events_outbox_on_dispatched_at" ON "events_outbox" ("dispatched_at") WHERE "dispatched_at" IS NOT NULL;
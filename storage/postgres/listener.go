@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// EventsChannel is the notification channel stored events are published to.
+const EventsChannel = "order_events"
+
+const listenRetryInterval = 5 * time.Second
+
+// outboxCatchUpMargin covers relay transactions that started before the last received
+// notification but committed after it, their records have an earlier dispatched_at.
+const outboxCatchUpMargin = time.Minute
+
+// eventsListener passes every outbox record to handle once, whether it comes
+// from a notification or from catching up after a reconnect.
+type eventsListener struct {
+	handle          func(payload []byte)
+	workflowChanged func()
+	since           time.Time           // dispatched_at of the latest received record, zero before the first connect
+	seen            map[int64]time.Time // Received records dispatched after since - outboxCatchUpMargin
+	prunedAt        time.Time
+}
+
+func (l *eventsListener) receive(record outboxNotification) {
+	if _, ok := l.seen[record.ID]; ok {
+		return
+	}
+	l.seen[record.ID] = record.DispatchedAt
+
+	if record.DispatchedAt.After(l.since) {
+		l.since = record.DispatchedAt
+	}
+	if l.since.Sub(l.prunedAt) > outboxCatchUpMargin {
+		for id, dispatchedAt := range l.seen {
+			if dispatchedAt.Before(l.since.Add(-outboxCatchUpMargin)) {
+				delete(l.seen, id)
+			}
+		}
+		l.prunedAt = l.since
+	}
+
+	l.handle(record.Event)
+}
+
+// ListenEvents holds a dedicated connection listening on EventsChannel and
+// passes the payload of every notification to handle. Notifications on
+// WorkflowChannel call workflowChanged. The connection is re-established on
+// errors until the context is canceled. Notifications sent while the connection
+// is down are lost, so after a reconnect the records dispatched since the last
+// received one are read from the outbox and workflowChanged is called.
+func (p *WebhookRepo) ListenEvents(ctx context.Context, handle func(payload []byte), workflowChanged func()) {
+	l := &eventsListener{
+		handle:          handle,
+		workflowChanged: workflowChanged,
+		seen:            make(map[int64]time.Time),
+	}
+
+	for {
+		err := p.listen(ctx, l)
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("Listening on %s failed: %v. Retrying in %s", EventsChannel, err, listenRetryInterval)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryInterval):
+		}
+	}
+}
+
+func (p *WebhookRepo) listen(ctx context.Context, l *eventsListener) error {
+	poolConn, err := p.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The listening connection is taken out of the pool, so it is never reused by queries.
+	conn := poolConn.Hijack()
+	defer conn.Close(context.Background())
+
+	for _, channel := range []string{EventsChannel, WorkflowChannel} {
+		if _, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return err
+		}
+	}
+	log.Printf("Listening on %s and %s", EventsChannel, WorkflowChannel)
+
+	if l.since.IsZero() {
+		if err = conn.QueryRow(ctx, "SELECT now()").Scan(&l.since); err != nil {
+			return err
+		}
+	} else {
+		l.workflowChanged()
+
+		records, err := p.dispatchedOutbox(ctx, l.since.Add(-outboxCatchUpMargin))
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			l.receive(record)
+		}
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		if notification.Channel == WorkflowChannel {
+			l.workflowChanged()
+			continue
+		}
+
+		var record outboxNotification
+		if err = json.Unmarshal([]byte(notification.Payload), &record); err != nil {
+			log.Printf("Error unmarshalling notification: %v", err)
+			continue
+		}
+
+		l.receive(record)
+	}
+}
@@ -33,7 +33,7 @@ func (p *OrdersRepo) GetOrdersByFilter(ctx context.Context, filters *models.Orde
 	for rows.Next() {
 		var event models.FullEventInfo
 		err = rows.Scan(&event.EventID, &event.OrderID, &event.UserID, &event.OrderStatusID, &event.CreatedAt,
-			&event.UpdatedAt, &event.OrderStatusName, &event.IsFinal)
+			&event.UpdatedAt, &event.OrderStatusName, &event.IsFinal, &event.OrderType)
 		if err != nil {
 			return nil, err
 		}
@@ -44,7 +44,7 @@ func (p *OrdersRepo) GetOrdersByFilter(ctx context.Context, filters *models.Orde
 }
 
 func buildQuery(filter *models.OrderFilter) (string, []interface{}) {
-	baseQuery := `SELECT e.event_id, e.order_id, e.user_id, e.order_status_id, e.created_at, e.updated_at, os.name AS order_status_name, os.is_final
+	baseQuery := `SELECT e.event_id, e.order_id, e.user_id, e.order_status_id, e.created_at, e.updated_at, os.name AS order_status_name, os.is_final, e.order_type
 			 FROM events e
 			 JOIN order_statuses os ON e.order_status_id = os.id
 			 WHERE 1=1`
@@ -65,6 +65,16 @@ func buildQuery(filter *models.OrderFilter) (string, []interface{}) {
 		paramIndex += len(filter.Status)
 	}
 
+	if len(filter.OrderType) != 0 {
+		placeholders := []string{}
+		for i := range filter.OrderType {
+			placeholders = append(placeholders, fmt.Sprintf("$%d", paramIndex+1+i))
+			params = append(params, filter.OrderType[i])
+		}
+		baseQuery += " AND e.order_type IN (" + strings.Join(placeholders, ", ") + ")"
+		paramIndex += len(filter.OrderType)
+	}
+
 	if filter.UserID != uuid.Nil {
 		paramIndex++
 		baseQuery += fmt.Sprintf(" AND user_id = $%d", paramIndex)
@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"sse/config"
+)
+
+// outboxCleanupInterval is how often dispatched outbox records older than the retention are deleted.
+const outboxCleanupInterval = time.Minute
+
+// outboxNotification is the payload of a notification on EventsChannel.
+type outboxNotification struct {
+	ID           int64           `json:"id"`
+	DispatchedAt time.Time       `json:"dispatched_at"`
+	Event        json.RawMessage `json:"event"`
+}
+
+// RelayOutbox publishes undispatched outbox records to EventsChannel until
+// the context is canceled. Records are published and marked as dispatched in
+// one transaction, so every stored event is published at least once.
+// Several instances may relay at the same time, locked records are skipped.
+// Dispatched records are kept for cfg.Retention, so listeners can catch up after a reconnect.
+func (p *WebhookRepo) RelayOutbox(ctx context.Context, cfg config.OutboxConfig) {
+	ticker := time.NewTicker(cfg.PollInterval.Std())
+	defer ticker.Stop()
+
+	var cleanedAt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if cfg.Retention > 0 && time.Since(cleanedAt) >= outboxCleanupInterval {
+			cleanedAt = time.Now()
+			if err := p.cleanupOutbox(ctx, cfg.Retention.Std()); err != nil && ctx.Err() == nil {
+				log.Printf("Error cleaning up outbox: %v", err)
+			}
+		}
+
+		for {
+			dispatched, err := p.dispatchOutbox(ctx, cfg.BatchSize)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Error dispatching outbox: %v", err)
+				}
+				break
+			}
+
+			if dispatched < cfg.BatchSize {
+				break
+			}
+		}
+	}
+}
+
+func (p *WebhookRepo) dispatchOutbox(ctx context.Context, batchSize int) (int, error) {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `SELECT id, payload
+			 FROM events_outbox
+			 WHERE dispatched_at IS NULL
+			 ORDER BY id ASC
+			 LIMIT @batchSize
+			 FOR UPDATE SKIP LOCKED`
+	rows, err := tx.Query(ctx, query, pgx.NamedArgs{"batchSize": batchSize})
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		ids      []int64
+		payloads []string
+	)
+	for rows.Next() {
+		var (
+			id      int64
+			payload string
+		)
+		if err = rows.Scan(&id, &payload); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+		payloads = append(payloads, payload)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	// Notifications are sent in the order they were issued when the transaction commits.
+	// now() is the transaction start time, the same as dispatched_at.
+	for i, payload := range payloads {
+		_, err = tx.Exec(ctx, `SELECT pg_notify(@channel, json_build_object(
+				'id', @id::bigint, 'dispatched_at', now(), 'event', @payload::json)::text)`, pgx.NamedArgs{
+			"channel": EventsChannel,
+			"id":      ids[i],
+			"payload": payload,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("unable to notify: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE events_outbox SET dispatched_at = now() WHERE id = ANY(@ids)`,
+		pgx.NamedArgs{"ids": ids})
+	if err != nil {
+		return 0, fmt.Errorf("unable to mark outbox rows dispatched: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return len(ids), nil
+}
+
+// cleanupOutbox deletes the records dispatched before the retention.
+func (p *WebhookRepo) cleanupOutbox(ctx context.Context, retention time.Duration) error {
+	_, err := p.db.Exec(ctx, `DELETE FROM events_outbox WHERE dispatched_at < now() - make_interval(secs => @retention)`,
+		pgx.NamedArgs{"retention": retention.Seconds()})
+
+	return err
+}
+
+// dispatchedOutbox returns the records dispatched at or after since, ordered by id.
+func (p *WebhookRepo) dispatchedOutbox(ctx context.Context, since time.Time) ([]outboxNotification, error) {
+	query := `SELECT id, dispatched_at, payload
+			 FROM events_outbox
+			 WHERE dispatched_at >= @since
+			 ORDER BY id ASC`
+	rows, err := p.db.Query(ctx, query, pgx.NamedArgs{"since": since})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []outboxNotification
+	for rows.Next() {
+		var (
+			record  outboxNotification
+			payload string
+		)
+		if err = rows.Scan(&record.ID, &record.DispatchedAt, &payload); err != nil {
+			return nil, err
+		}
+		record.Event = json.RawMessage(payload)
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
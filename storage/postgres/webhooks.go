@@ -3,8 +3,10 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -20,31 +22,53 @@ func (p *Postgres) NewWebhookRepo() *WebhookRepo {
 	return &WebhookRepo{p}
 }
 
-func (p *WebhookRepo) AddEvent(ctx context.Context, event models.Event) error {
+// AddEvent stores the event together with its outbox record in one transaction.
+// The outbox relay publishes the record to all listening instances.
+func (p *WebhookRepo) AddEvent(ctx context.Context, event models.Event, eventMsg models.EventMsg) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	query := `INSERT INTO events (event_id, order_id, user_id, order_status_id, updated_at, created_at) 
-		VALUES (@eventID, @orderID, @userID, @orderStatusID, @updatedAt, @createdAt)`
+	payload, err := json.Marshal(eventMsg)
+	if err != nil {
+		return err
+	}
+
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `INSERT INTO events (event_id, order_id, user_id, order_type, order_status_id, updated_at, created_at) 
+		VALUES (@eventID, @orderID, @userID, @orderType, @orderStatusID, @updatedAt, @createdAt)`
 	args := pgx.NamedArgs{
 		"eventID":       event.EventID,
 		"orderID":       event.OrderID,
 		"userID":        event.UserID,
+		"orderType":     event.OrderType,
 		"orderStatusID": event.OrderStatusID,
 		"updatedAt":     event.UpdatedAt,
 		"createdAt":     event.CreatedAt,
 	}
 
-	_, err := p.db.Exec(ctx, query, args)
+	_, err = tx.Exec(ctx, query, args)
 	if err != nil {
 		return fmt.Errorf("unable to insert row: %w", err)
 	}
 
-	return nil
+	_, err = tx.Exec(ctx, `INSERT INTO events_outbox (event_id, payload) VALUES (@eventID, @payload)`, pgx.NamedArgs{
+		"eventID": event.EventID,
+		"payload": string(payload),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to insert outbox row: %w", err)
+	}
+
+	return tx.Commit(ctx)
 }
 
 func (p *WebhookRepo) GetOrderEvents(ctx context.Context, orderID uuid.UUID) ([]models.FullEventInfo, error) {
-	query := `SELECT e.event_id, e.order_id, e.user_id, e.order_status_id, e.created_at, e.updated_at, os.name AS order_status_name, os.is_final
+	query := `SELECT e.event_id, e.order_id, e.user_id, e.order_status_id, e.created_at, e.updated_at, os.name AS order_status_name, os.is_final, e.order_type
 			 FROM events e
 			 JOIN order_statuses os ON e.order_status_id = os.id
 			 WHERE e.order_id = @orderID
@@ -65,7 +89,7 @@ func (p *WebhookRepo) GetOrderEvents(ctx context.Context, orderID uuid.UUID) ([]
 	for rows.Next() {
 		var event models.FullEventInfo
 		err = rows.Scan(&event.EventID, &event.OrderID, &event.UserID, &event.OrderStatusID, &event.CreatedAt,
-			&event.UpdatedAt, &event.OrderStatusName, &event.IsFinal)
+			&event.UpdatedAt, &event.OrderStatusName, &event.IsFinal, &event.OrderType)
 		if err != nil {
 			return nil, err
 		}
@@ -75,6 +99,114 @@ func (p *WebhookRepo) GetOrderEvents(ctx context.Context, orderID uuid.UUID) ([]
 	return events, nil
 }
 
+// GetRecentOrderEvents returns the limit latest order events updated at or after since,
+// ordered by update time. Zero since and limit mean no bound.
+func (p *WebhookRepo) GetRecentOrderEvents(
+	ctx context.Context, orderID uuid.UUID, since time.Time, limit int,
+) ([]models.FullEventInfo, error) {
+	query := `SELECT * FROM (
+				SELECT e.event_id, e.order_id, e.user_id, e.order_status_id, e.created_at, e.updated_at, os.name AS order_status_name, os.is_final, e.order_type
+				FROM events e
+				JOIN order_statuses os ON e.order_status_id = os.id
+				WHERE e.order_id = @orderID AND e.updated_at >= @since
+				ORDER BY e.updated_at DESC
+				LIMIT @limit
+			 ) recent
+			 ORDER BY updated_at ASC`
+	args := pgx.NamedArgs{
+		"orderID": orderID,
+		"since":   since,
+		"limit":   nil, // LIMIT NULL returns all rows
+	}
+	if limit > 0 {
+		args["limit"] = limit
+	}
+
+	rows, err := p.db.Query(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.FullEventInfo
+	for rows.Next() {
+		var event models.FullEventInfo
+		err = rows.Scan(&event.EventID, &event.OrderID, &event.UserID, &event.OrderStatusID, &event.CreatedAt,
+			&event.UpdatedAt, &event.OrderStatusName, &event.IsFinal, &event.OrderType)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// GetUserEvents returns all events of the ordersLimit most recently updated orders of the user.
+func (p *WebhookRepo) GetUserEvents(ctx context.Context, userID uuid.UUID, ordersLimit int) ([]models.FullEventInfo, error) {
+	query := `WITH recent_orders AS (
+				SELECT order_id
+				FROM events
+				WHERE user_id = @userID
+				GROUP BY order_id
+				ORDER BY max(updated_at) DESC
+				LIMIT @ordersLimit
+			 )
+			 SELECT e.event_id, e.order_id, e.user_id, e.order_status_id, e.created_at, e.updated_at, os.name AS order_status_name, os.is_final, e.order_type
+			 FROM events e
+			 JOIN order_statuses os ON e.order_status_id = os.id
+			 WHERE e.order_id IN (SELECT order_id FROM recent_orders)
+			 ORDER BY e.updated_at ASC`
+	args := pgx.NamedArgs{
+		"userID":      userID,
+		"ordersLimit": ordersLimit,
+	}
+
+	rows, err := p.db.Query(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.FullEventInfo
+	for rows.Next() {
+		var event models.FullEventInfo
+		err = rows.Scan(&event.EventID, &event.OrderID, &event.UserID, &event.OrderStatusID, &event.CreatedAt,
+			&event.UpdatedAt, &event.OrderStatusName, &event.IsFinal, &event.OrderType)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+func (p *WebhookRepo) GetFullEventByID(ctx context.Context, eventID uuid.UUID) (*models.FullEventInfo, error) {
+	query := `
+		SELECT e.event_id, e.order_id, e.user_id, e.order_status_id, e.created_at, e.updated_at, os.name AS order_status_name, os.is_final, e.order_type
+			FROM events e
+			JOIN order_statuses os ON e.order_status_id = os.id
+			WHERE e.event_id = @eventID
+	`
+	args := pgx.NamedArgs{
+		"eventID": eventID,
+	}
+
+	var res models.FullEventInfo
+	err := p.db.QueryRow(ctx, query, args).
+		Scan(&res.EventID, &res.OrderID, &res.UserID, &res.OrderStatusID, &res.CreatedAt,
+			&res.UpdatedAt, &res.OrderStatusName, &res.IsFinal, &res.OrderType)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &res, nil
+}
+
 func (p *WebhookRepo) GetOrderStatusByName(ctx context.Context, name string) (*models.OrderStatus, error) {
 	query := `
 		SELECT id, name, is_final
@@ -88,6 +220,9 @@ func (p *WebhookRepo) GetOrderStatusByName(ctx context.Context, name string) (*m
 	var res models.OrderStatus
 	err := p.db.QueryRow(ctx, query, args).Scan(&res.ID, &res.Name, &res.IsFinal)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: unknown order status %q", models.ErrBadRequest, name)
+		}
 		return nil, err
 	}
 
@@ -121,7 +256,7 @@ func (p *WebhookRepo) GetEventByID(ctx context.Context, eventID uuid.UUID) (*mod
 
 func (p *WebhookRepo) GetLastUpdatedEventByOrderID(ctx context.Context, orderID uuid.UUID) (*models.FullEventInfo, error) {
 	query := `
-		SELECT e.event_id, e.order_id, e.user_id, e.order_status_id, e.created_at, e.updated_at, os.name AS order_status_name, os.is_final
+		SELECT e.event_id, e.order_id, e.user_id, e.order_status_id, e.created_at, e.updated_at, os.name AS order_status_name, os.is_final, e.order_type
 			FROM events e
 			JOIN order_statuses os ON e.order_status_id = os.id
 			WHERE e.order_id = @orderID 
@@ -137,7 +272,7 @@ func (p *WebhookRepo) GetLastUpdatedEventByOrderID(ctx context.Context, orderID
 	// Execute the query
 	err := p.db.QueryRow(ctx, query, args).
 		Scan(&res.EventID, &res.OrderID, &res.UserID, &res.OrderStatusID, &res.CreatedAt,
-			&res.UpdatedAt, &res.OrderStatusName, &res.IsFinal)
+			&res.UpdatedAt, &res.OrderStatusName, &res.IsFinal, &res.OrderType)
 	if err != nil {
 		if !errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"sse/models"
+)
+
+// WorkflowChannel is the notification channel instances are told to reload the workflow on.
+const WorkflowChannel = "workflow_changes"
+
+type WorkflowRepo struct {
+	*Postgres
+}
+
+func (p *Postgres) NewWorkflowRepo() *WorkflowRepo {
+	return &WorkflowRepo{p}
+}
+
+// GetWorkflowDefinition reads the order statuses and the transitions between them.
+func (p *WorkflowRepo) GetWorkflowDefinition(ctx context.Context) (*models.WorkflowDefinition, error) {
+	var res models.WorkflowDefinition
+
+	rows, err := p.db.Query(ctx, `SELECT id, name, is_final FROM order_statuses ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var status models.OrderStatus
+		if err = rows.Scan(&status.ID, &status.Name, &status.IsFinal); err != nil {
+			return nil, err
+		}
+		res.Statuses = append(res.Statuses, status)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT t.order_type, COALESCE(fs.name, ''), ts.name, COALESCE(EXTRACT(EPOCH FROM t.time_window), 0)::float8
+			 FROM order_status_transitions t
+			 LEFT JOIN order_statuses fs ON t.from_status_id = fs.id
+			 JOIN order_statuses ts ON t.to_status_id = ts.id
+			 ORDER BY t.id`
+
+	rows, err = p.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			transition    models.StatusTransition
+			windowSeconds float64
+		)
+		if err = rows.Scan(&transition.OrderType, &transition.From, &transition.To, &windowSeconds); err != nil {
+			return nil, err
+		}
+		transition.Window = time.Duration(windowSeconds * float64(time.Second))
+		res.Transitions = append(res.Transitions, transition)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// AddOrderStatus stores a new order status. It returns models.ErrAlreadyExists if the status exists.
+func (p *WorkflowRepo) AddOrderStatus(ctx context.Context, status models.OrderStatus) error {
+	query := `INSERT INTO order_statuses (name, is_final)
+		SELECT @name, @isFinal
+		WHERE NOT EXISTS (SELECT 1 FROM order_statuses WHERE name = @name)`
+	args := pgx.NamedArgs{
+		"name":    status.Name,
+		"isFinal": status.IsFinal,
+	}
+
+	tag, err := p.db.Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("unable to insert row: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrAlreadyExists
+	}
+
+	return nil
+}
+
+// AddStatusTransition stores a new transition between existing statuses.
+// It returns models.ErrAlreadyExists if the transition exists.
+func (p *WorkflowRepo) AddStatusTransition(ctx context.Context, transition models.StatusTransition) error {
+	query := `INSERT INTO order_status_transitions (order_type, from_status_id, to_status_id, time_window)
+		SELECT @orderType, (SELECT id FROM order_statuses WHERE name = @from), ts.id,
+			CASE WHEN @windowSeconds::float8 > 0 THEN make_interval(secs => @windowSeconds::float8) END
+			FROM order_statuses ts
+			WHERE ts.name = @to
+		ON CONFLICT DO NOTHING`
+	args := pgx.NamedArgs{
+		"orderType":     transition.OrderType,
+		"from":          transition.From,
+		"to":            transition.To,
+		"windowSeconds": transition.Window.Seconds(),
+	}
+
+	tag, err := p.db.Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("unable to insert row: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrAlreadyExists
+	}
+
+	return nil
+}
+
+// NotifyWorkflowChanged tells every instance listening with ListenEvents to reload the workflow.
+func (p *WorkflowRepo) NotifyWorkflowChanged(ctx context.Context) error {
+	_, err := p.db.Exec(ctx, `SELECT pg_notify(@channel, '')`, pgx.NamedArgs{"channel": WorkflowChannel})
+	if err != nil {
+		return fmt.Errorf("unable to notify: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package workflow
+
+import (
+	"sync/atomic"
+	"time"
+
+	"sse/models"
+)
+
+// GiveMyMoneyBackTimeout is how long the money can be given back after chinazes.
+const GiveMyMoneyBackTimeout = 30 * time.Second
+
+// Default is the payment order workflow. It is used until the workflows are
+// loaded from the order_status_transitions table.
+var Default = models.WorkflowDefinition{
+	Statuses: []models.OrderStatus{
+		{Name: models.CoolOrderCreated},
+		{Name: models.SBUVarificationPending},
+		{Name: models.ConfirmedByMayor},
+		{Name: models.ChangedMyMind, IsFinal: true},
+		{Name: models.Failed, IsFinal: true},
+		{Name: models.Chinazes, IsFinal: true},
+		{Name: models.GiveMyMoneyBack, IsFinal: true},
+	},
+	Transitions: []models.StatusTransition{
+		{To: models.CoolOrderCreated},
+		{From: models.CoolOrderCreated, To: models.SBUVarificationPending},
+		{From: models.CoolOrderCreated, To: models.ChangedMyMind},
+		{From: models.CoolOrderCreated, To: models.Failed},
+		{From: models.SBUVarificationPending, To: models.ConfirmedByMayor},
+		{From: models.SBUVarificationPending, To: models.ChangedMyMind},
+		{From: models.SBUVarificationPending, To: models.Failed},
+		{From: models.ConfirmedByMayor, To: models.Chinazes},
+		{From: models.ConfirmedByMayor, To: models.ChangedMyMind},
+		{From: models.ConfirmedByMayor, To: models.Failed},
+		{From: models.Chinazes, To: models.GiveMyMoneyBack, Window: GiveMyMoneyBackTimeout},
+	},
+}
+
+// workflows are the workflows in use by order type.
+type workflows struct {
+	definition models.WorkflowDefinition
+	byType     map[string]*Workflow
+}
+
+var current atomic.Pointer[workflows]
+
+func init() {
+	Load(Default)
+}
+
+// Load replaces the workflows in use, e.g. after they are reloaded from the database.
+func Load(definition models.WorkflowDefinition) {
+	transitions := make(map[string][]models.StatusTransition)
+	for _, t := range definition.Transitions {
+		transitions[orderType(t.OrderType)] = append(transitions[orderType(t.OrderType)], t)
+	}
+
+	byType := make(map[string]*Workflow, len(transitions))
+	for typ, typeTransitions := range transitions {
+		byType[typ] = New(definition.Statuses, typeTransitions)
+	}
+
+	current.Store(&workflows{definition: definition, byType: byType})
+}
+
+// Definition returns the definition of the workflows in use.
+func Definition() models.WorkflowDefinition {
+	return current.Load().definition
+}
+
+// Exists reports whether there is a workflow for the order type.
+func Exists(typ string) bool {
+	_, ok := current.Load().byType[orderType(typ)]
+	return ok
+}
+
+// For returns the workflow of the order type. Orders of unknown types follow
+// the workflow of models.DefaultOrderType.
+func For(typ string) *Workflow {
+	w := current.Load()
+	if workflow, ok := w.byType[orderType(typ)]; ok {
+		return workflow
+	}
+	if workflow, ok := w.byType[models.DefaultOrderType]; ok {
+		return workflow
+	}
+
+	return New(nil, nil)
+}
+
+func orderType(typ string) string {
+	if len(typ) == 0 {
+		return models.DefaultOrderType
+	}
+
+	return typ
+}
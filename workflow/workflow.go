@@ -0,0 +1,134 @@
+// Package workflow defines the order statuses and the transitions allowed between them.
+package workflow
+
+import (
+	"time"
+
+	"sse/models"
+)
+
+// Step is an order status and the time the order reached it.
+type Step struct {
+	Status string
+	At     time.Time
+}
+
+type Workflow struct {
+	initial     map[string]bool
+	final       map[string]bool
+	transitions map[string]map[string]time.Duration
+}
+
+// New creates a workflow from the statuses and the transitions of a single
+// order type. Final statuses are kept unless a transition from them is still allowed.
+func New(statuses []models.OrderStatus, transitions []models.StatusTransition) *Workflow {
+	w := &Workflow{
+		initial:     make(map[string]bool),
+		final:       make(map[string]bool),
+		transitions: make(map[string]map[string]time.Duration),
+	}
+
+	for _, status := range statuses {
+		w.final[status.Name] = status.IsFinal
+	}
+	for _, t := range transitions {
+		if len(t.From) == 0 {
+			w.initial[t.To] = true
+			continue
+		}
+
+		if _, ok := w.transitions[t.From]; !ok {
+			w.transitions[t.From] = make(map[string]time.Duration)
+		}
+		w.transitions[t.From][t.To] = t.Window
+	}
+
+	return w
+}
+
+func (w *Workflow) IsFinal(status string) bool {
+	return w.final[status]
+}
+
+// CanTransition reports whether the order can move from the step straight to
+// the status at the given time. A nil step means the order has no status yet.
+func (w *Workflow) CanTransition(from *Step, to string, at time.Time) bool {
+	if from == nil {
+		return w.initial[to]
+	}
+
+	window, ok := w.transitions[from.Status][to]
+	if !ok {
+		return false
+	}
+
+	return window == 0 || !at.After(from.At.Add(window))
+}
+
+// Precedes reports whether the order passes the status before it can reach the other one.
+func (w *Workflow) Precedes(status, other string) bool {
+	visited := map[string]bool{status: true}
+	next := []string{status}
+	for len(next) != 0 {
+		current := next[len(next)-1]
+		next = next[:len(next)-1]
+
+		for to := range w.transitions[current] {
+			if to == other {
+				return true
+			}
+			if !visited[to] {
+				visited[to] = true
+				next = append(next, to)
+			}
+		}
+	}
+
+	return false
+}
+
+// Accept checks an event with the status that arrives when the order is at the step.
+// Events are delivered out of order, so statuses the order has already passed
+// and statuses it can still reach are accepted.
+func (w *Workflow) Accept(from Step, to string, at time.Time) error {
+	if w.Precedes(to, from.Status) {
+		return nil
+	}
+
+	if window, ok := w.transitions[from.Status][to]; ok {
+		if window != 0 && at.After(from.At.Add(window)) {
+			return models.ErrTransitionWindowExpired
+		}
+		return nil
+	}
+
+	if w.final[from.Status] {
+		return models.ErrAlreadyExistsFinalStatus
+	}
+
+	if to == from.Status || w.Precedes(from.Status, to) {
+		return nil
+	}
+
+	return models.ErrInvalidTransition
+}
+
+// EndsAt returns the time after which the order at the step can no longer
+// change. It reports false if the order can still change at any time.
+func (w *Workflow) EndsAt(step Step) (time.Time, bool) {
+	if !w.final[step.Status] {
+		return time.Time{}, false
+	}
+
+	endsAt := step.At
+	for _, window := range w.transitions[step.Status] {
+		if window == 0 {
+			return time.Time{}, false
+		}
+		if step.At.Add(window).After(endsAt) {
+			endsAt = step.At.Add(window)
+		}
+	}
+
+	return endsAt, true
+}
@@ -0,0 +1,116 @@
+package workflow
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"sse/models"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestCanTransition(t *testing.T) {
+	w := New(Default.Statuses, Default.Transitions)
+	refundDeadline := start.Add(GiveMyMoneyBackTimeout)
+
+	tests := []struct {
+		name string
+		from *Step
+		to   string
+		at   time.Time
+		want bool
+	}{
+		{"initial status", nil, models.CoolOrderCreated, start, true},
+		{"not an initial status", nil, models.SBUVarificationPending, start, false},
+		{"next status", &Step{models.CoolOrderCreated, start}, models.SBUVarificationPending, start, true},
+		{"skipped status", &Step{models.CoolOrderCreated, start}, models.ConfirmedByMayor, start, false},
+		{"previous status", &Step{models.SBUVarificationPending, start}, models.CoolOrderCreated, start, false},
+		{"same status", &Step{models.CoolOrderCreated, start}, models.CoolOrderCreated, start, false},
+		{"from final status", &Step{models.Failed, start}, models.Chinazes, start, false},
+		{"window start", &Step{models.Chinazes, start}, models.GiveMyMoneyBack, start, true},
+		{"window end", &Step{models.Chinazes, start}, models.GiveMyMoneyBack, refundDeadline, true},
+		{"window expired", &Step{models.Chinazes, start}, models.GiveMyMoneyBack, refundDeadline.Add(time.Nanosecond), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := w.CanTransition(tt.from, tt.to, tt.at); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccept(t *testing.T) {
+	w := New(Default.Statuses, Default.Transitions)
+	refundDeadline := start.Add(GiveMyMoneyBackTimeout)
+
+	// A branch that can't be reached from the other one.
+	branches := New(
+		[]models.OrderStatus{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+		[]models.StatusTransition{{To: "a"}, {From: "a", To: "b"}, {From: "a", To: "c"}},
+	)
+
+	tests := []struct {
+		name string
+		w    *Workflow
+		from Step
+		to   string
+		at   time.Time
+		want error
+	}{
+		{"next status", w, Step{models.CoolOrderCreated, start}, models.SBUVarificationPending, start, nil},
+		{"status ahead", w, Step{models.CoolOrderCreated, start}, models.Chinazes, start, nil},
+		{"same status", w, Step{models.SBUVarificationPending, start}, models.SBUVarificationPending, start, nil},
+		{"predecessor", w, Step{models.ConfirmedByMayor, start}, models.CoolOrderCreated, start, nil},
+		{"predecessor of final status", w, Step{models.Chinazes, start}, models.SBUVarificationPending, start, nil},
+		{"predecessor after window", w, Step{models.Chinazes, start}, models.ConfirmedByMayor, refundDeadline.Add(time.Hour), nil},
+		{"final to final", w, Step{models.Failed, start}, models.Chinazes, start, models.ErrAlreadyExistsFinalStatus},
+		{"final to other branch final", w, Step{models.Chinazes, start}, models.ChangedMyMind, start, models.ErrAlreadyExistsFinalStatus},
+		{"same final status", w, Step{models.Failed, start}, models.Failed, start, models.ErrAlreadyExistsFinalStatus},
+		{"window end", w, Step{models.Chinazes, start}, models.GiveMyMoneyBack, refundDeadline, nil},
+		{"window expired", w, Step{models.Chinazes, start}, models.GiveMyMoneyBack, refundDeadline.Add(time.Nanosecond), models.ErrTransitionWindowExpired},
+		{"unknown status", w, Step{models.CoolOrderCreated, start}, "teleported", start, models.ErrInvalidTransition},
+		{"other branch", branches, Step{"b", start}, "c", start, models.ErrInvalidTransition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.w.Accept(tt.from, tt.to, tt.at); !errors.Is(got, tt.want) || (got == nil) != (tt.want == nil) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndsAt(t *testing.T) {
+	w := New(Default.Statuses, Default.Transitions)
+
+	// Refunds are allowed at any time.
+	anyTimeRefund := New(Default.Statuses, append(Default.Transitions[:len(Default.Transitions):len(Default.Transitions)],
+		models.StatusTransition{From: models.Failed, To: models.GiveMyMoneyBack}))
+
+	tests := []struct {
+		name   string
+		w      *Workflow
+		step   Step
+		want   time.Time
+		wantOK bool
+	}{
+		{"not final", w, Step{models.ConfirmedByMayor, start}, time.Time{}, false},
+		{"final", w, Step{models.Failed, start}, start, true},
+		{"final with window", w, Step{models.Chinazes, start}, start.Add(GiveMyMoneyBackTimeout), true},
+		{"final after window", w, Step{models.GiveMyMoneyBack, start}, start, true},
+		{"final without window", anyTimeRefund, Step{models.Failed, start}, time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.w.EndsAt(tt.step)
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Errorf("got %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}